@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+	"github.com/hydrolix/plugin/pkg/macros"
+)
+
+// Error codes of resource responses, frontend callers tell a bad request or query apart from a plugin failure by
+// the code
+const (
+	// CodeBadRequest is a malformed request body, invalid parameters or macros called with invalid arguments
+	CodeBadRequest = "BAD_REQUEST"
+	// CodeInvalidQuery is a query which doesn't parse or which ClickHouse rejects
+	CodeInvalidQuery = "INVALID_QUERY"
+	// CodeDatabase is a failure to connect to or query ClickHouse
+	CodeDatabase = "DATABASE_ERROR"
+	// CodeInternal is a failure of the plugin, e.g. a recovered panic
+	CodeInternal = "INTERNAL_ERROR"
+)
+
+// Error is an error of a resource route with its HTTP status and code
+type Error struct {
+	Status int
+	Code   string
+	// Position of the parser error in the query, nil for other errors
+	Position *Position
+	err      error
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Position is the location of an error in a query, with the offsets and 1-based line and column of Diagnostic
+type Position struct {
+	Start  int `json:"start"`
+	End    int `json:"end"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// ErrorResponse is the payload of failed resource requests
+type ErrorResponse struct {
+	Error        bool      `json:"error"`
+	ErrorMessage string    `json:"errorMessage"`
+	ErrorCode    string    `json:"errorCode"`
+	Position     *Position `json:"position,omitempty"`
+}
+
+func badRequest(err error) error {
+	return &Error{Status: http.StatusBadRequest, Code: CodeBadRequest, err: err}
+}
+
+func invalidQuery(err error) error {
+	return &Error{Status: http.StatusUnprocessableEntity, Code: CodeInvalidQuery, err: err}
+}
+
+// interpolationError is a bad request for macros called with invalid arguments, an invalid query for parser errors
+// located in query and an internal error otherwise
+func interpolationError(query string, err error) error {
+	switch {
+	case errors.Is(err, sqlutil.ErrorBadArgumentCount), errors.Is(err, macros.ErrArguments),
+		// the interpolator reports unclosed macro arguments by message only
+		strings.Contains(err.Error(), "failed to parse macro arguments"):
+		return badRequest(err)
+	case parserErrorRegex.MatchString(err.Error()):
+		return parserError(query, err)
+	default:
+		return err
+	}
+}
+
+// parserError is an invalid query error located at the position of the parser error in query, errors without
+// position aren't located
+func parserError(query string, err error) error {
+	if !parserErrorRegex.MatchString(err.Error()) {
+		return invalidQuery(err)
+	}
+	i := parserIssue(query, err)
+	positions := newPositions(query)
+	start, end := positions.at(i.start), positions.at(i.end)
+	return &Error{
+		Status:   http.StatusUnprocessableEntity,
+		Code:     CodeInvalidQuery,
+		Position: &Position{Start: start.offset, End: end.offset, Line: start.line, Column: start.column},
+		err:      err,
+	}
+}
+
+// databaseError is an invalid query error for exceptions of ClickHouse, which rejected the query, and a database
+// error otherwise
+func databaseError(err error) error {
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) {
+		return invalidQuery(err)
+	}
+	return &Error{Status: http.StatusBadGateway, Code: CodeDatabase, err: err}
+}
+
+// wrapError writes the status and payload of an error, errors other than Error are internal errors
+func wrapError(rw http.ResponseWriter, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		apiErr = &Error{Status: http.StatusInternalServerError, Code: CodeInternal, err: err}
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(apiErr.Status)
+	marshal, _ := json.Marshal(ErrorResponse{
+		true,
+		err.Error(),
+		apiErr.Code,
+		apiErr.Position,
+	})
+	_, _ = rw.Write(marshal)
+}
+
+// recoverPanic is deferred by resource routes, it logs a panic of the route with its stack trace and responds with
+// an internal error
+func recoverPanic(rw http.ResponseWriter, req *http.Request) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	log.DefaultLogger.FromContext(req.Context()).Error("resource route panicked",
+		"path", req.URL.Path, "panic", r, "stack", string(debug.Stack()))
+	wrapError(rw, fmt.Errorf("internal error: %v", r))
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+	"github.com/hydrolix/plugin/pkg/macros"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func errorResponse(t *testing.T, rw *httptest.ResponseRecorder) ErrorResponse {
+	var res ErrorResponse
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &res))
+	return res
+}
+
+func TestWrapError(t *testing.T) {
+	query := "SELECT *\nFROM logs.access\nWHERE status =="
+	for name, tt := range map[string]struct {
+		err    error
+		status int
+		code   string
+	}{
+		"bad request":   {badRequest(errors.New("unexpected EOF")), http.StatusBadRequest, CodeBadRequest},
+		"invalid query": {invalidQuery(errors.New("unknown macro")), http.StatusUnprocessableEntity, CodeInvalidQuery},
+		"exception":     {databaseError(&clickhouse.Exception{Code: 47, Message: "Unknown identifier"}), http.StatusUnprocessableEntity, CodeInvalidQuery},
+		"database":      {databaseError(errors.New("connection refused")), http.StatusBadGateway, CodeDatabase},
+		"internal":      {errors.New("nil map"), http.StatusInternalServerError, CodeInternal},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			wrapError(rw, tt.err)
+			assert.Equal(t, tt.status, rw.Code)
+			assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+			res := errorResponse(t, rw)
+			assert.True(t, res.Error)
+			assert.Equal(t, tt.err.Error(), res.ErrorMessage)
+			assert.Equal(t, tt.code, res.ErrorCode)
+			assert.Nil(t, res.Position)
+		})
+	}
+
+	rw := httptest.NewRecorder()
+	wrapError(rw, parserError(query, errors.New("line 2:13 expected expression")))
+	assert.Equal(t, http.StatusUnprocessableEntity, rw.Code)
+	res := errorResponse(t, rw)
+	assert.Equal(t, CodeInvalidQuery, res.ErrorCode)
+	assert.Equal(t, &Position{Start: 39, End: 41, Line: 3, Column: 14}, res.Position)
+
+	rw = httptest.NewRecorder()
+	wrapError(rw, parserError(query, errors.New("failed to parse macro arguments")))
+	assert.Equal(t, http.StatusUnprocessableEntity, rw.Code)
+	assert.Nil(t, errorResponse(t, rw).Position)
+}
+
+func TestInterpolationError(t *testing.T) {
+	query := "SELECT $__timeFilter()\nFROM logs.access"
+	for name, tt := range map[string]struct {
+		err    error
+		status int
+		code   string
+	}{
+		"argument count":    {fmt.Errorf("%w: macro $__dateFilter expects 1 argument", sqlutil.ErrorBadArgumentCount), http.StatusBadRequest, CodeBadRequest},
+		"missing argument":  {fmt.Errorf("interpolate: %w", macros.ErrArguments), http.StatusBadRequest, CodeBadRequest},
+		"unclosed argument": {errors.New("failed to parse macro arguments (missing close bracket?)"), http.StatusBadRequest, CodeBadRequest},
+		"parser":            {errors.New("line 1:7 expected expression"), http.StatusUnprocessableEntity, CodeInvalidQuery},
+		"internal":          {errors.New("context canceled"), http.StatusInternalServerError, CodeInternal},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			wrapError(rw, interpolationError(query, tt.err))
+			assert.Equal(t, tt.status, rw.Code)
+			assert.Equal(t, tt.code, errorResponse(t, rw).ErrorCode)
+		})
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	rw := httptest.NewRecorder()
+	func() {
+		defer recoverPanic(rw, httptest.NewRequest(http.MethodPost, "/format", strings.NewReader("")))
+		panic("nil map")
+	}()
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+	res := errorResponse(t, rw)
+	assert.Equal(t, CodeInternal, res.ErrorCode)
+	assert.Equal(t, "internal error: nil map", res.ErrorMessage)
+}
+
+func TestWriteResponse(t *testing.T) {
+	rw := httptest.NewRecorder()
+	writeResponse(rw, []string{"a"})
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error":false,"errorMessage":"","data":["a"]}`, rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	writeResponse(rw, map[string]any{"f": func() {}})
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+	assert.Equal(t, CodeInternal, errorResponse(t, rw).ErrorCode)
+}
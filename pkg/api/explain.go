@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hydrolix/sqlds/v5"
+)
+
+// EXPLAIN modes supported by the explain resource
+const (
+	ExplainPlan     = "plan"
+	ExplainPipeline = "pipeline"
+	ExplainEstimate = "estimate"
+)
+
+type ExplainData struct {
+	QueryData
+	// Mode is one of plan (default), pipeline and estimate
+	Mode string `json:"mode"`
+}
+
+// Explanation is the EXPLAIN output of the interpolated SQL of a query, only the field of the mode is set
+type Explanation struct {
+	Mode      string         `json:"mode"`
+	SQL       string         `json:"sql"`
+	Plan      []PlanNode     `json:"plan,omitempty"`
+	Pipeline  []PipelineLine `json:"pipeline,omitempty"`
+	Estimates []Estimate     `json:"estimates,omitempty"`
+}
+
+// PlanNode is a step of the query plan, Details holds the properties of the step other than its type and description
+type PlanNode struct {
+	Type        string         `json:"type"`
+	Description string         `json:"description,omitempty"`
+	Details     map[string]any `json:"details,omitempty"`
+	Children    []PlanNode     `json:"children,omitempty"`
+}
+
+// PipelineLine is a line of the query pipeline, Depth is its indentation level
+type PipelineLine struct {
+	Depth int    `json:"depth"`
+	Text  string `json:"text"`
+}
+
+// Estimate is the number of parts, rows and marks a query reads from a table
+type Estimate struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Parts    uint64 `json:"parts"`
+	Rows     uint64 `json:"rows"`
+	Marks    uint64 `json:"marks"`
+}
+
+// Explain interpolates the SQL of a query and returns its EXPLAIN PLAN, PIPELINE or ESTIMATE output
+func Explain(ds *sqlds.HydrolixDatasource, db DBFunc, rw http.ResponseWriter, req *http.Request) {
+	defer recoverPanic(rw, req)
+	var request Request[ExplainData]
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		wrapError(rw, badRequest(err))
+		return
+	}
+
+	query, err := interpolate(ds, req, request.Data.QueryData)
+	if err != nil {
+		wrapError(rw, err)
+		return
+	}
+	conn, err := db(req.Context(), req.Header)
+	if err != nil {
+		wrapError(rw, databaseError(err))
+		return
+	}
+	body, err := explain(req.Context(), conn, request.Data.Mode, query)
+	if err != nil {
+		wrapError(rw, err)
+		return
+	}
+
+	writeResponse(rw, body)
+}
+
+// explain runs EXPLAIN of mode for an interpolated query
+func explain(ctx context.Context, db *sql.DB, mode, query string) (Explanation, error) {
+	if mode == "" {
+		mode = ExplainPlan
+	}
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	explanation := Explanation{Mode: mode, SQL: query}
+
+	var err error
+	switch mode {
+	case ExplainPlan:
+		var lines []string
+		if lines, err = explainLines(ctx, db, "EXPLAIN PLAN json = 1, indexes = 1 "+query); err == nil {
+			explanation.Plan, err = parsePlan(strings.Join(lines, "\n"))
+		}
+	case ExplainPipeline:
+		var lines []string
+		if lines, err = explainLines(ctx, db, "EXPLAIN PIPELINE "+query); err == nil {
+			explanation.Pipeline = parsePipeline(lines)
+		}
+	case ExplainEstimate:
+		explanation.Estimates, err = explainEstimate(ctx, db, query)
+	default:
+		return explanation, badRequest(fmt.Errorf("unsupported explain mode: %q", mode))
+	}
+	return explanation, err
+}
+
+// explainLines returns the lines of an EXPLAIN output
+func explainLines(ctx context.Context, db *sql.DB, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	defer rows.Close()
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, databaseError(err)
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return lines, nil
+}
+
+func explainEstimate(ctx context.Context, db *sql.DB, query string) ([]Estimate, error) {
+	rows, err := db.QueryContext(ctx, "EXPLAIN ESTIMATE "+query)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	defer rows.Close()
+	estimates := []Estimate{}
+	for rows.Next() {
+		var e Estimate
+		if err := rows.Scan(&e.Database, &e.Table, &e.Parts, &e.Rows, &e.Marks); err != nil {
+			return nil, databaseError(err)
+		}
+		estimates = append(estimates, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+	return estimates, nil
+}
+
+// parsePlan parses the output of EXPLAIN PLAN json = 1, a plan per statement
+func parsePlan(output string) ([]PlanNode, error) {
+	var plans []struct {
+		Plan map[string]any `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(output), &plans); err != nil {
+		return nil, fmt.Errorf("cannot parse query plan: %w", err)
+	}
+	nodes := make([]PlanNode, len(plans))
+	for i, p := range plans {
+		nodes[i] = planNode(p.Plan)
+	}
+	return nodes, nil
+}
+
+func planNode(step map[string]any) PlanNode {
+	node := PlanNode{}
+	for key, value := range step {
+		switch key {
+		case "Node Type":
+			node.Type, _ = value.(string)
+		case "Description":
+			node.Description, _ = value.(string)
+		case "Plans":
+			children, _ := value.([]any)
+			for _, child := range children {
+				if c, ok := child.(map[string]any); ok {
+					node.Children = append(node.Children, planNode(c))
+				}
+			}
+		default:
+			if node.Details == nil {
+				node.Details = map[string]any{}
+			}
+			node.Details[key] = value
+		}
+	}
+	return node
+}
+
+// parsePipeline splits EXPLAIN PIPELINE lines into their indentation level, 2 spaces, and text
+func parsePipeline(lines []string) []PipelineLine {
+	pipeline := make([]PipelineLine, 0, len(lines))
+	for _, line := range lines {
+		text := strings.TrimLeft(line, " ")
+		if text == "" {
+			continue
+		}
+		pipeline = append(pipeline, PipelineLine{Depth: (len(line) - len(text)) / 2, Text: text})
+	}
+	return pipeline
+}
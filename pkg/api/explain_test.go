@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const explainPlanOutput = `[
+  {
+    "Plan": {
+      "Node Type": "Expression",
+      "Description": "(Project names + Projection)",
+      "Plans": [
+        {
+          "Node Type": "ReadFromMergeTree",
+          "Description": "logs.access",
+          "Indexes": [{"Type": "MinMax", "Keys": ["timestamp"], "Selected Parts": 2}]
+        }
+      ]
+    }
+  }
+]`
+
+func TestExplain(t *testing.T) {
+	db, d := openTestDB(t, func(query string) *testRows {
+		switch {
+		case strings.HasPrefix(query, "EXPLAIN PLAN"):
+			return &testRows{columns: []string{"explain"}, values: [][]driver.Value{{explainPlanOutput}}}
+		case strings.HasPrefix(query, "EXPLAIN PIPELINE"):
+			return &testRows{columns: []string{"explain"}, values: [][]driver.Value{
+				{"(Expression)"}, {"ExpressionTransform"}, {"  (ReadFromMergeTree)"}, {"  MergeTreeSelect 0 → 1"},
+			}}
+		default:
+			return &testRows{columns: []string{"database", "table", "parts", "rows", "marks"}, values: [][]driver.Value{
+				{"logs", "access", uint64(2), uint64(8192), uint64(1)},
+			}}
+		}
+	})
+	query := "SELECT * FROM logs.access;"
+
+	plan, err := explain(context.Background(), db, "", query)
+	require.NoError(t, err)
+	assert.Equal(t, ExplainPlan, plan.Mode)
+	assert.Equal(t, "SELECT * FROM logs.access", plan.SQL)
+	require.Len(t, plan.Plan, 1)
+	assert.Equal(t, "Expression", plan.Plan[0].Type)
+	assert.Equal(t, "(Project names + Projection)", plan.Plan[0].Description)
+	require.Len(t, plan.Plan[0].Children, 1)
+	read := plan.Plan[0].Children[0]
+	assert.Equal(t, "ReadFromMergeTree", read.Type)
+	assert.Contains(t, read.Details, "Indexes")
+	assert.Nil(t, read.Children)
+
+	pipeline, err := explain(context.Background(), db, ExplainPipeline, query)
+	require.NoError(t, err)
+	assert.Equal(t, []PipelineLine{
+		{0, "(Expression)"}, {0, "ExpressionTransform"}, {1, "(ReadFromMergeTree)"}, {1, "MergeTreeSelect 0 → 1"},
+	}, pipeline.Pipeline)
+
+	estimate, err := explain(context.Background(), db, ExplainEstimate, query)
+	require.NoError(t, err)
+	assert.Equal(t, []Estimate{{Database: "logs", Table: "access", Parts: 2, Rows: 8192, Marks: 1}}, estimate.Estimates)
+
+	assert.Equal(t, []string{
+		"EXPLAIN PLAN json = 1, indexes = 1 SELECT * FROM logs.access",
+		"EXPLAIN PIPELINE SELECT * FROM logs.access",
+		"EXPLAIN ESTIMATE SELECT * FROM logs.access",
+	}, d.queries)
+
+	_, err = explain(context.Background(), db, "syntax", query)
+	assert.EqualError(t, err, `unsupported explain mode: "syntax"`)
+}
+
+func TestExplainScanError(t *testing.T) {
+	db, _ := openTestDB(t, func(string) *testRows {
+		return &testRows{columns: []string{"database", "table", "parts", "rows", "marks"}, values: [][]driver.Value{
+			{"logs", "access", "many", uint64(8192), uint64(1)},
+		}}
+	})
+	_, err := explain(context.Background(), db, ExplainEstimate, "SELECT * FROM logs.access")
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, CodeDatabase, apiErr.Code)
+}
@@ -0,0 +1,432 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hydrolix/clickhouse-sql-parser/parser"
+)
+
+// Keyword cases of formatted SQL
+const (
+	KeywordCaseUpper    = "upper"
+	KeywordCaseLower    = "lower"
+	KeywordCasePreserve = "preserve"
+)
+
+const (
+	defaultIndentWidth = 2
+	maxIndentWidth     = 8
+)
+
+type FormatData struct {
+	Query string `json:"query"`
+	// KeywordCase is one of upper (default), lower and preserve
+	KeywordCase string `json:"keywordCase"`
+	// IndentWidth is the number of spaces of an indentation level, 2 by default
+	IndentWidth int `json:"indentWidth"`
+}
+
+// formatKeywords are the keywords whose case is changed by formatting, words also used as column names, like time
+// units, are kept as written
+var formatKeywords = map[string]bool{}
+
+// functionKeywords are keywords which are also function names, e.g. left(s, 3), kept as written before a parenthesis
+var functionKeywords = map[string]bool{"LEFT": true, "RIGHT": true, "ANY": true, "REPLACE": true, "FORMAT": true}
+
+// joinKeywords are the keywords which may precede JOIN
+var joinKeywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`ALL AND ANTI ANY ARRAY AS ASC ASOF BETWEEN BY CASE CROSS CUBE DESC DISTINCT ELSE
+		END EXCEPT EXISTS FALSE FILL FINAL FORMAT FROM FULL GLOBAL GROUP HAVING ILIKE IN INNER INTERSECT INTERVAL IS
+		JOIN LEFT LIKE LIMIT NOT NULL NULLS OFFSET ON OR ORDER OUTER OVER PARTITION PREWHERE QUALIFY RIGHT ROLLUP
+		SAMPLE SELECT SEMI SETTINGS THEN TIES TOTALS TRUE UNION USING WHEN WHERE WINDOW WITH`) {
+		formatKeywords[k] = true
+	}
+	for _, k := range strings.Fields(`GLOBAL LEFT RIGHT INNER FULL OUTER CROSS ANY ALL SEMI ANTI ASOF ARRAY PASTE`) {
+		joinKeywords[k] = true
+	}
+}
+
+// Format pretty-prints the statements of a query
+func Format(rw http.ResponseWriter, req *http.Request) {
+	defer recoverPanic(rw, req)
+	var request Request[FormatData]
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		wrapError(rw, badRequest(err))
+		return
+	}
+
+	body, err := format(request.Data)
+	if err != nil {
+		wrapError(rw, err)
+		return
+	}
+
+	writeResponse(rw, body)
+}
+
+// format checks the statements of a query parse and re-emits their tokens with canonical spacing, one clause per
+// line and items of lists and conditions indented on their own lines. The tokens are emitted rather than the AST:
+// the AST has no comments, and its String() rewrites macros, template variables and query parameters, which are only
+// expanded after formatting. Expressions, e.g. CASE, lambdas and parametric aggregates, stay on one line, so only
+// clauses, lists, conditions and subqueries need the structure of the statement, which the tokens give.
+func format(data FormatData) (string, error) {
+	switch data.KeywordCase {
+	case "":
+		data.KeywordCase = KeywordCaseUpper
+	case KeywordCaseUpper, KeywordCaseLower, KeywordCasePreserve:
+	default:
+		return "", badRequest(fmt.Errorf("unsupported keyword case: %q", data.KeywordCase))
+	}
+	if data.IndentWidth == 0 {
+		data.IndentWidth = defaultIndentWidth
+	} else if data.IndentWidth < 0 || data.IndentWidth > maxIndentWidth {
+		return "", badRequest(fmt.Errorf("indent width must be between 1 and %d: %d", maxIndentWidth, data.IndentWidth))
+	}
+	if _, err := parser.NewParser(data.Query).ParseStmts(); err != nil {
+		return "", parserError(data.Query, err)
+	}
+
+	f := &formatter{
+		query:       data.Query,
+		tokens:      lex(data.Query, true),
+		keywordCase: data.KeywordCase,
+		indent:      strings.Repeat(" ", data.IndentWidth),
+		atLineStart: true,
+		scope:       formatScope{start: true},
+	}
+	return f.format(), nil
+}
+
+// formatScope is the state of a statement or a subquery
+type formatScope struct {
+	subquery bool
+	// level is the indentation level of the clauses
+	level int
+	// clause is the current clause, e.g. SELECT or GROUP BY
+	clause string
+	// depth is the number of open parentheses of expressions
+	depth int
+	// betweens is the number of BETWEEN waiting for their AND
+	betweens int
+	// start is set until the first token of the scope
+	start bool
+	// openLine is the indentation level of the line opening a subquery
+	openLine int
+}
+
+type formatter struct {
+	query       string
+	tokens      []token
+	keywordCase string
+	indent      string
+
+	out strings.Builder
+	// line is the indentation level of the current line
+	line        int
+	atLineStart bool
+	// newline is set when the next token starts a new line, after a line comment
+	newline  bool
+	previous token
+	// unary is set when the previous token is a sign
+	unary bool
+
+	scope  formatScope
+	scopes []formatScope
+}
+
+func (f *formatter) format() string {
+	for i := 0; i < len(f.tokens); i++ {
+		t := f.tokens[i]
+		switch {
+		case t.kind == tokenComment:
+			if i > 0 && strings.Contains(f.query[f.tokens[i-1].end:t.start], "\n") {
+				// comments on their own lines before a clause are aligned with the clause
+				level := f.line
+				if next := f.next(i); next >= 0 {
+					if n, _ := f.clauseAt(next); n > 0 {
+						level = f.scope.level
+					}
+				}
+				f.breakLine(level)
+			}
+			f.write(t.text, t, t)
+			if !strings.HasPrefix(t.text, "/*") ||
+				i+1 < len(f.tokens) && strings.Contains(f.query[t.end:f.tokens[i+1].start], "\n") {
+				f.newline = true
+			}
+			continue
+		case t.is(";"):
+			f.write(t.text, t, t)
+			if f.next(i) >= 0 {
+				// a blank line separates statements
+				f.out.WriteString("\n")
+				f.breakLine(0)
+			}
+			f.scope, f.scopes, f.newline = formatScope{start: true}, nil, false
+			f.previous = token{}
+			continue
+		}
+
+		if n, clause := f.clauseAt(i); n > 0 {
+			i = f.writeClause(i, n, clause)
+			continue
+		}
+		f.scope.start = false
+
+		switch {
+		case t.kind == tokenMacro && i+1 < len(f.tokens) && f.tokens[i+1].is("(") && f.tokens[i+1].start == t.end,
+			t.is("{"):
+			// macro arguments and query parameters are kept as written
+			open := i
+			if !t.is("{") {
+				open++
+			}
+			if end := f.matching(open); end > 0 {
+				f.write(f.query[t.start:f.tokens[end].end], t, f.tokens[end])
+				i = end
+				continue
+			}
+			f.write(t.text, t, t)
+		case t.is("("):
+			f.write(t.text, t, t)
+			if next := f.next(i); next >= 0 && f.tokens[next].isKeyword("SELECT", "WITH") {
+				f.scopes = append(f.scopes, f.scope)
+				f.scope = formatScope{subquery: true, level: f.line + 1, start: true, openLine: f.line}
+			} else {
+				f.scope.depth++
+			}
+		case t.is(")"):
+			if f.scope.depth == 0 && f.scope.subquery {
+				f.breakLine(f.scope.openLine)
+				f.scope, f.scopes = f.scopes[len(f.scopes)-1], f.scopes[:len(f.scopes)-1]
+			} else if f.scope.depth > 0 {
+				f.scope.depth--
+			}
+			f.write(t.text, t, t)
+		case t.is(",") && f.scope.depth == 0 && listClauses[f.scope.clause]:
+			f.write(t.text, t, t)
+			i = f.trailingComments(i)
+			f.breakLine(f.scope.level + 1)
+		case t.isKeyword("BETWEEN") && f.scope.depth == 0:
+			f.scope.betweens++
+			f.write(f.keyword(i), t, t)
+		case t.isKeyword("AND", "OR") && f.scope.depth == 0 && conditionClauses[f.scope.clause]:
+			if t.isKeyword("AND") && f.scope.betweens > 0 {
+				f.scope.betweens--
+			} else {
+				f.breakLine(f.scope.level + 1)
+			}
+			f.write(f.keyword(i), t, t)
+		default:
+			f.write(f.keyword(i), t, t)
+		}
+	}
+	return f.out.String()
+}
+
+// listClauses are clauses whose items are indented on their own lines
+var listClauses = map[string]bool{
+	"WITH": true, "SELECT": true, "WHERE": true, "PREWHERE": true, "GROUP BY": true, "HAVING": true,
+	"QUALIFY": true, "ORDER BY": true, "SETTINGS": true,
+}
+
+// conditionClauses are clauses whose AND and OR operands are on their own lines
+var conditionClauses = map[string]bool{"WHERE": true, "PREWHERE": true, "HAVING": true, "QUALIFY": true}
+
+// clauseAt returns the number of tokens of the clause keywords at index i, and the name of the clause. Keywords of a
+// clause are contiguous, comments between them end the clause keywords.
+func (f *formatter) clauseAt(i int) (int, string) {
+	t := f.tokens[i]
+	if t.kind != tokenIdent || f.scope.depth > 0 || f.previous.is(".") || f.previous.isKeyword("AS") {
+		return 0, ""
+	}
+	next := f.next(i)
+	if next >= 0 && f.tokens[next].is("(") && functionKeywords[strings.ToUpper(t.text)] {
+		return 0, ""
+	}
+	nextIs := func(keywords ...string) bool { return next == i+1 && f.tokens[next].isKeyword(keywords...) }
+
+	switch name := strings.ToUpper(t.text); {
+	case name == "WITH":
+		if f.scope.start {
+			return 1, name
+		}
+	case name == "SELECT":
+		if nextIs("DISTINCT") {
+			return 2, name
+		}
+		return 1, name
+	case name == "GROUP" || name == "ORDER":
+		if nextIs("BY") {
+			return 2, name + " BY"
+		}
+	case name == "UNION" || name == "EXCEPT" || name == "INTERSECT":
+		if nextIs("ALL", "DISTINCT") {
+			return 2, name
+		}
+		if nextIs("SELECT") || next >= 0 && f.tokens[next].is("(") {
+			return 1, name
+		}
+	case name == "FROM" || name == "WHERE" || name == "PREWHERE" || name == "HAVING" || name == "QUALIFY" ||
+		name == "WINDOW" || name == "LIMIT" || name == "SETTINGS" || name == "FORMAT" || name == "JOIN":
+		return 1, name
+	case joinKeywords[name]:
+		for j := i; j < len(f.tokens); j++ {
+			switch {
+			case f.tokens[j].isKeyword("JOIN"):
+				return j - i + 1, "JOIN"
+			case f.tokens[j].kind != tokenIdent || !joinKeywords[strings.ToUpper(f.tokens[j].text)]:
+				return 0, ""
+			}
+		}
+	}
+	return 0, ""
+}
+
+// writeClause starts a line with the n keywords of a clause, items of list clauses, or of clauses followed by a line
+// comment, start on the next line. It returns the index of the last token written.
+func (f *formatter) writeClause(i, n int, clause string) int {
+	f.breakLine(f.scope.level)
+	for j := i; j < i+n; j++ {
+		f.write(f.keyword(j), f.tokens[j], f.tokens[j])
+	}
+	f.scope.clause, f.scope.start, f.scope.betweens = clause, false, 0
+	last := f.trailingComments(i + n - 1)
+	if listClauses[clause] || f.newline {
+		f.breakLine(f.scope.level + 1)
+	}
+	return last
+}
+
+// trailingComments writes the comments following the token at index i on the same line, before the formatter breaks
+// the line after the token. It returns the index of the last token written.
+func (f *formatter) trailingComments(i int) int {
+	for i+1 < len(f.tokens) && f.tokens[i+1].kind == tokenComment &&
+		!strings.Contains(f.query[f.tokens[i].end:f.tokens[i+1].start], "\n") {
+		i++
+		t := f.tokens[i]
+		f.write(t.text, t, t)
+		f.newline = f.newline || !strings.HasPrefix(t.text, "/*")
+	}
+	return i
+}
+
+// breakLine starts a new line at the indentation level
+func (f *formatter) breakLine(level int) {
+	f.line = level
+	if !f.atLineStart {
+		f.out.WriteString("\n")
+		f.atLineStart = true
+	}
+}
+
+// write emits text spanning tokens first to last
+func (f *formatter) write(text string, first, last token) {
+	if f.newline {
+		f.newline = false
+		f.breakLine(f.line)
+	}
+	if f.atLineStart {
+		f.out.WriteString(strings.Repeat(f.indent, f.line))
+		f.atLineStart = false
+	} else if f.space(first) {
+		f.out.WriteString(" ")
+	}
+	f.out.WriteString(text)
+	f.unary = (first.is("-") || first.is("+")) && !endsExpression(f.previous) &&
+		f.previous.kind != tokenVariable && f.previous.kind != tokenMacro
+	f.previous = last
+}
+
+// space tells whether a space separates the previous token from t
+func (f *formatter) space(t token) bool {
+	p := f.previous
+	switch {
+	case f.unary, p.is("("), p.is("["), p.is("."), p.is("::"):
+		return false
+	case t.is(","), t.is(")"), t.is("]"), t.is("."), t.is("::"), t.is(";"):
+		return false
+	case t.is("("):
+		// function calls and parametric aggregates, e.g. quantile(0.9)(x)
+		return !isFormatCall(p)
+	case t.is("["):
+		return !endsExpression(p) && p.kind != tokenVariable
+	}
+	return true
+}
+
+// isFormatCall tells whether a parenthesis following the token opens the arguments of a call
+func isFormatCall(t token) bool {
+	upper := strings.ToUpper(t.text)
+	switch t.kind {
+	case tokenIdent:
+		return !formatKeywords[upper] || functionKeywords[upper]
+	case tokenMacro, tokenQuotedIdent:
+		return true
+	}
+	return t.is(")")
+}
+
+// endsExpression tells whether the token ends an operand, so a following sign is a binary operator and a following
+// bracket an index
+func endsExpression(t token) bool {
+	switch t.kind {
+	case tokenIdent:
+		return !formatKeywords[strings.ToUpper(t.text)]
+	case tokenQuotedIdent, tokenString, tokenNumber:
+		return true
+	case tokenPunct:
+		return t.is(")") || t.is("]")
+	}
+	return false
+}
+
+// keyword returns the text of the token at index i, in the keyword case when it's a keyword
+func (f *formatter) keyword(i int) string {
+	t := f.tokens[i]
+	upper := strings.ToUpper(t.text)
+	if t.kind != tokenIdent || !formatKeywords[upper] || f.keywordCase == KeywordCasePreserve ||
+		f.previous.is(".") || f.previous.isKeyword("AS") {
+		return t.text
+	}
+	if next := f.next(i); next >= 0 && f.tokens[next].is("(") && functionKeywords[upper] {
+		return t.text
+	}
+	if f.keywordCase == KeywordCaseLower {
+		return strings.ToLower(t.text)
+	}
+	return upper
+}
+
+// next returns the index of the token following i, skipping comments, or -1
+func (f *formatter) next(i int) int {
+	for i++; i < len(f.tokens); i++ {
+		if f.tokens[i].kind != tokenComment {
+			return i
+		}
+	}
+	return -1
+}
+
+// matching returns the index of the bracket closing the bracket at index i, or -1
+func (f *formatter) matching(i int) int {
+	open := f.tokens[i].text
+	close := map[string]string{"(": ")", "{": "}"}[open]
+	depth := 0
+	for j := i; j < len(f.tokens); j++ {
+		switch {
+		case f.tokens[j].is(open):
+			depth++
+		case f.tokens[j].is(close):
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const formatQuery = "-- errors per minute\n" +
+	"select $__timeInterval(timestamp) as time, count() c, quantile(0.9)(duration) p90 /* slow */\n" +
+	"from logs.access where $__timeFilter( timestamp ) and status between 500 and 599 and (method='GET' or " +
+	"method = 'POST') and host in (${hosts:singlequote}) and id in (select id from logs.errors)\n" +
+	" group by time order by time desc limit 100 settings max_threads=1; select left(s, 3), -1 from logs.access"
+
+func TestFormat(t *testing.T) {
+	formatted, err := format(FormatData{Query: formatQuery})
+	require.NoError(t, err)
+	assert.Equal(t, `-- errors per minute
+SELECT
+  $__timeInterval(timestamp) AS time,
+  count() c,
+  quantile(0.9)(duration) p90 /* slow */
+FROM logs.access
+WHERE
+  $__timeFilter( timestamp )
+  AND status BETWEEN 500 AND 599
+  AND (method = 'GET' OR method = 'POST')
+  AND host IN (${hosts:singlequote})
+  AND id IN (
+    SELECT
+      id
+    FROM logs.errors
+  )
+GROUP BY
+  time
+ORDER BY
+  time DESC
+LIMIT 100
+SETTINGS
+  max_threads = 1;
+
+SELECT
+  left(s, 3),
+  -1
+FROM logs.access`, formatted)
+
+	again, err := format(FormatData{Query: formatted})
+	require.NoError(t, err)
+	assert.Equal(t, formatted, again)
+}
+
+func TestFormatOptions(t *testing.T) {
+	formatted, err := format(FormatData{
+		Query:       "SELECT a, b FROM logs.access WHERE a = 1 -- first\nAND b = 2",
+		KeywordCase: KeywordCaseLower,
+		IndentWidth: 4,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "select\n    a,\n    b\nfrom logs.access\nwhere\n    a = 1 -- first\n    and b = 2", formatted)
+
+	formatted, err = format(FormatData{Query: "Select a From logs.access", KeywordCase: KeywordCasePreserve})
+	require.NoError(t, err)
+	assert.Equal(t, "Select\n  a\nFrom logs.access", formatted)
+
+	_, err = format(FormatData{Query: "SELECT 1", KeywordCase: "title"})
+	assert.EqualError(t, err, `unsupported keyword case: "title"`)
+	_, err = format(FormatData{Query: "SELECT 1", IndentWidth: 9})
+	assert.EqualError(t, err, "indent width must be between 1 and 8: 9")
+}
+
+func TestFormatStatements(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name: "case",
+			query: "select case when status>=500 then 'error' else 'ok' end as class, " +
+				"case status when 200 then 1 else 0 end from logs.access",
+			want: "SELECT\n" +
+				"  CASE WHEN status >= 500 THEN 'error' ELSE 'ok' END AS class,\n" +
+				"  CASE status WHEN 200 THEN 1 ELSE 0 END\n" +
+				"FROM logs.access",
+		},
+		{
+			name: "lambdas",
+			query: "select arrayMap(x->x*2, values) doubled, arrayFilter((k, v) -> v > 0, keys, values) " +
+				"from logs.access where arrayExists(x->x='a', tags)",
+			want: "SELECT\n" +
+				"  arrayMap(x -> x * 2, values) doubled,\n" +
+				"  arrayFilter((k, v) -> v > 0, keys, values)\n" +
+				"FROM logs.access\n" +
+				"WHERE\n" +
+				"  arrayExists(x -> x = 'a', tags)",
+		},
+		{
+			name:  "parametric aggregates",
+			query: "select quantile(0.9)(duration), quantilesIf(0.5, 0.99) (duration, status=200) from logs.access",
+			want: "SELECT\n" +
+				"  quantile(0.9)(duration),\n" +
+				"  quantilesIf(0.5, 0.99)(duration, status = 200)\n" +
+				"FROM logs.access",
+		},
+		{
+			name: "nested subqueries",
+			query: "select host from (select host, count() c from (select host from logs.access where status = 500) " +
+				"group by host) where c > 10",
+			want: "SELECT\n" +
+				"  host\n" +
+				"FROM (\n" +
+				"  SELECT\n" +
+				"    host,\n" +
+				"    count() c\n" +
+				"  FROM (\n" +
+				"    SELECT\n" +
+				"      host\n" +
+				"    FROM logs.access\n" +
+				"    WHERE\n" +
+				"      status = 500\n" +
+				"  )\n" +
+				"  GROUP BY\n" +
+				"    host\n" +
+				")\n" +
+				"WHERE\n" +
+				"  c > 10",
+		},
+		{
+			name: "comments",
+			query: "/* top hosts */\nselect -- columns\n host, -- the host\n count() c /* total */ " +
+				"from -- table\n logs.access\n-- grouped\ngroup by host",
+			want: "/* top hosts */\n" +
+				"SELECT -- columns\n" +
+				"  host, -- the host\n" +
+				"  count() c /* total */\n" +
+				"FROM -- table\n" +
+				"  logs.access\n" +
+				"-- grouped\n" +
+				"GROUP BY\n" +
+				"  host",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, err := format(FormatData{Query: tt.query})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, formatted)
+
+			again, err := format(FormatData{Query: formatted})
+			require.NoError(t, err)
+			assert.Equal(t, formatted, again)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	// tokenQuotedIdent is an identifier quoted by backquotes or double quotes
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	// tokenMacro is a macro with its escaping dollar signs, e.g. $__timeFilter or $$__timeFilter
+	tokenMacro
+	// tokenVariable is a template variable, e.g. $var or ${var:csv}
+	tokenVariable
+	tokenPunct
+	// tokenComment is a -- or # line comment, without its newline, or a /* */ block comment
+	tokenComment
+)
+
+// token is a lexical token of a query, start and end are byte offsets
+type token struct {
+	kind       tokenKind
+	text       string
+	start, end int
+}
+
+func (t token) is(punct string) bool {
+	return t.kind == tokenPunct && t.text == punct
+}
+
+func (t token) isKeyword(keywords ...string) bool {
+	if t.kind != tokenIdent {
+		return false
+	}
+	for _, k := range keywords {
+		if strings.EqualFold(t.text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// operators are the punctuation tokens of several characters
+var operators = []string{"->", "::", "<=", ">=", "!=", "<>", "==", "||"}
+
+// lex splits a query into tokens, comments are tokens when comments is set. It never fails: unterminated strings
+// and quoted identifiers run to the end of the query.
+func lex(query string, comments bool) []token {
+	var tokens []token
+	for i := 0; i < len(query); {
+		r, size := utf8.DecodeRuneInString(query[i:])
+		start := i
+		kind := tokenPunct
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case strings.HasPrefix(query[i:], "--") || r == '#':
+			kind, i = tokenComment, lineEnd(query, i)
+		case strings.HasPrefix(query[i:], "/*"):
+			kind, i = tokenComment, len(query)
+			if end := strings.Index(query[start+2:], "*/"); end >= 0 {
+				i = start + end + 4
+			}
+		case r == '\'':
+			kind, i = tokenString, quoteEnd(query, i)
+		case r == '`' || r == '"':
+			kind, i = tokenQuotedIdent, quoteEnd(query, i)
+		case r == '$':
+			j := i
+			for j < len(query) && query[j] == '$' {
+				j++
+			}
+			if strings.HasPrefix(query[j:], "__") {
+				kind, i = tokenMacro, wordEnd(query, j)
+			} else if strings.HasPrefix(query[j:], "{") {
+				kind, i = tokenVariable, len(query)
+				if end := strings.IndexByte(query[j:], '}'); end >= 0 {
+					i = j + end + 1
+				}
+			} else {
+				kind, i = tokenVariable, wordEnd(query, j)
+			}
+		case unicode.IsDigit(r):
+			kind, i = tokenNumber, wordEnd(query, i)
+			// decimals and exponents, e.g. 1.5 and 1e-5
+			for i < len(query) && (query[i] == '.' || query[i] == '+' || query[i] == '-') &&
+				(query[i] == '.' && i+1 < len(query) && isDigit(query[i+1]) ||
+					query[i] != '.' && (query[i-1] == 'e' || query[i-1] == 'E')) {
+				i = wordEnd(query, i+1)
+			}
+		case r == '_' || unicode.IsLetter(r):
+			kind, i = tokenIdent, wordEnd(query, i)
+		case slices.ContainsFunc(operators, func(op string) bool { return strings.HasPrefix(query[start:], op) }):
+			i += 2
+		default:
+			i += size
+		}
+		text := query[start:i]
+		if kind == tokenComment {
+			if !comments {
+				continue
+			}
+			text = strings.TrimRight(text, "\r")
+		}
+		tokens = append(tokens, token{kind: kind, text: text, start: start, end: i})
+	}
+	return tokens
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func lineEnd(query string, i int) int {
+	if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(query)
+}
+
+// quoteEnd returns the end of the string or quoted identifier starting at i, quotes are escaped by a backslash or
+// doubled
+func quoteEnd(query string, i int) int {
+	quote := query[i]
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			j++
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+func wordEnd(query string, i int) int {
+	for i < len(query) {
+		r, size := utf8.DecodeRuneInString(query[i:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
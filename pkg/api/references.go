@@ -0,0 +1,145 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/hydrolix/clickhouse-sql-parser/parser"
+)
+
+// tableRef is a database.table read by a SELECT of the query. The time filter of the SELECT is inserted at filterAt,
+// after the WHERE keyword or the clauses preceding WHERE.
+type tableRef struct {
+	database, table string
+	start, end      int
+	filterAt        int
+	filterText      string
+}
+
+// references are the tables and identifiers of the statements of a query. Nodes are walked by reflection, so every
+// node type of the parser is traversed and identifiers are told apart by the fields holding them: names of
+// functions, types, settings and qualified identifiers are *Ident fields, identifiers held by expression fields
+// refer to columns or to names the query defines.
+type references struct {
+	selects int
+	with    bool
+	tables  []tableRef
+	// columns are identifiers of expressions, defined are names of aliases and lambda parameters
+	columns []*parser.Ident
+	defined map[string]bool
+}
+
+func newReferences(stmts []parser.Expr) *references {
+	r := &references{defined: map[string]bool{}}
+	for _, stmt := range stmts {
+		r.walk(reflect.ValueOf(stmt), false, false, reflect.Value{})
+	}
+	return r
+}
+
+// single tells whether the query is a single SELECT of one table, without CTEs, joins, unions or subqueries, so its
+// identifiers resolve to columns of that table.
+func (r *references) single() bool {
+	return r.selects == 1 && !r.with && len(r.tables) == 1
+}
+
+// walk visits v, expr is set for values held by expression fields and defining for values held by aliases and lambda
+// parameters. selectQuery is the innermost SELECT holding v.
+func (r *references) walk(v reflect.Value, expr, defining bool, selectQuery reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			r.walk(v.Elem(), true, defining, selectQuery)
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		if ident, ok := v.Interface().(*parser.Ident); ok {
+			r.ident(ident, expr, defining)
+			return
+		}
+		r.walk(v.Elem(), expr, defining, selectQuery)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			r.walk(v.Index(i), expr, defining, selectQuery)
+		}
+	case reflect.Struct:
+		r.walkStruct(v, defining, selectQuery)
+	}
+}
+
+func (r *references) walkStruct(v reflect.Value, defining bool, selectQuery reflect.Value) {
+	name := v.Type().Name()
+	switch name {
+	case "SelectQuery":
+		r.selects++
+		if with := v.FieldByName("With"); with.IsValid() && !with.IsZero() {
+			r.with = true
+		}
+		selectQuery = v
+	case "TableIdentifier":
+		r.table(v, selectQuery)
+		return
+	}
+	// x -> expr is a binary operation of the parser, (x, y) -> expr as well
+	lambda := strings.Contains(name, "Lambda")
+	if op := v.FieldByName("Operation"); op.IsValid() && op.Kind() == reflect.String && op.String() == "->" {
+		lambda = true
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldDefining := defining || field.Name == "Alias" ||
+			lambda && (field.Name == "LeftExpr" || field.Name == "Params")
+		r.walk(v.Field(i), false, fieldDefining, selectQuery)
+	}
+}
+
+func (r *references) ident(ident *parser.Ident, expr, defining bool) {
+	switch {
+	case defining:
+		r.defined[ident.Name] = true
+	case expr && !strings.HasPrefix(ident.Name, "$"):
+		// macros and template variables aren't columns
+		r.columns = append(r.columns, ident)
+	}
+}
+
+// table adds the reference of a TableIdentifier node, tables without database aren't references
+func (r *references) table(v reflect.Value, selectQuery reflect.Value) {
+	database, _ := v.FieldByName("Database").Interface().(*parser.Ident)
+	table, _ := v.FieldByName("Table").Interface().(*parser.Ident)
+	if database == nil || table == nil || !selectQuery.IsValid() {
+		return
+	}
+	ref := tableRef{
+		database: database.Name,
+		table:    table.Name,
+		start:    int(database.Pos()),
+		end:      int(table.End()),
+	}
+	if where, ok := clause(selectQuery, "Where"); ok {
+		ref.filterAt, ref.filterText = int(where.Pos())+len("WHERE"), " $__timeFilter() AND"
+	} else {
+		for _, name := range []string{"From", "ArrayJoin", "Prewhere"} {
+			if c, ok := clause(selectQuery, name); ok {
+				ref.filterAt = max(ref.filterAt, int(c.End()))
+			}
+		}
+		ref.filterText = " WHERE $__timeFilter()"
+	}
+	r.tables = append(r.tables, ref)
+}
+
+// clause returns the clause of a SELECT held by the field name, false when the SELECT has none
+func clause(selectQuery reflect.Value, name string) (parser.Expr, bool) {
+	f := selectQuery.FieldByName(name)
+	if !f.IsValid() || f.IsZero() {
+		return nil, false
+	}
+	c, ok := f.Interface().(parser.Expr)
+	return c, ok
+}
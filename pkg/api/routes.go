@@ -2,7 +2,6 @@ package api
 
 import (
 	"encoding/json"
-	"errors"
 	"maps"
 	"net/http"
 	"slices"
@@ -14,132 +13,107 @@ import (
 )
 
 func AST(rw http.ResponseWriter, req *http.Request) {
-	defer func() {
-		if r := recover(); r != nil {
-			wrapError(rw, errors.New("Unknown Error"))
-		}
-	}()
+	defer recoverPanic(rw, req)
 	var astRequest Request[ASTData]
 	if err := json.NewDecoder(req.Body).Decode(&astRequest); err != nil {
-		wrapError(rw, err)
+		wrapError(rw, badRequest(err))
 		return
 	}
 
 	body, err := parser.NewParser(astRequest.Data.Query).ParseStmts()
 	if err != nil {
-		wrapError(rw, err)
+		wrapError(rw, parserError(astRequest.Data.Query, err))
 		return
 
 	}
 
-	rw.WriteHeader(http.StatusOK)
-	marshal, err := json.Marshal(Response[[]parser.Expr]{
-		false,
-		"",
-		body,
-	})
-	_, err = rw.Write(marshal)
+	writeResponse(rw, body)
 }
 func Interpolate(ds *sqlds.HydrolixDatasource, rw http.ResponseWriter, req *http.Request) {
-	defer func() {
-		if r := recover(); r != nil {
-			rawMessage, _ := json.Marshal(r)
-			wrapError(rw, errors.New((string(rawMessage))))
-		}
-	}()
+	defer recoverPanic(rw, req)
 	var request Request[QueryData]
 	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
-		wrapError(rw, err)
+		wrapError(rw, badRequest(err))
 		return
 	}
-	timeRange := request.Data.Range.ToTimeRange()
-	interval, err := time.ParseDuration(request.Data.Interval)
+
+	body, err := interpolate(ds, req, request.Data)
 
 	if err != nil {
 		wrapError(rw, err)
 		return
+
 	}
 
-	body, err := ds.Interpolator.Interpolate(req.Context(),
+	writeResponse(rw, body)
+}
+
+// interpolate expands the macros and variables of the SQL of a query
+func interpolate(ds *sqlds.HydrolixDatasource, req *http.Request, data QueryData) (string, error) {
+	interval, err := time.ParseDuration(data.Interval)
+	if err != nil {
+		return "", badRequest(err)
+	}
+	sql, err := ds.Interpolator.Interpolate(req.Context(),
 		&sqlds.HDXQuery{
-			RawSQL:    request.Data.RawSql,
-			Filters:   request.Data.Filters,
-			Round:     request.Data.Round,
+			RawSQL:    data.RawSql,
+			Filters:   data.Filters,
+			Round:     data.Round,
 			Interval:  interval,
-			TimeRange: timeRange,
+			TimeRange: data.Range.ToTimeRange(),
 			Headers:   req.Header,
 		})
-
 	if err != nil {
-		wrapError(rw, err)
-		return
-
+		return "", interpolationError(data.RawSql, err)
 	}
-
-	rw.WriteHeader(http.StatusOK)
-	marshal, err := json.Marshal(Response[string]{
-		false,
-		"",
-		body,
-	})
-	_, err = rw.Write(marshal)
-
+	return sql, nil
 }
 
 func MacroCTEs(rw http.ResponseWriter, req *http.Request) {
-	defer func() {
-		if r := recover(); r != nil {
-			wrapError(rw, errors.New("Unknown Error"))
-		}
-	}()
+	defer recoverPanic(rw, req)
 	var astRequest Request[ASTData]
 	if err := json.NewDecoder(req.Body).Decode(&astRequest); err != nil {
-		wrapError(rw, err)
+		wrapError(rw, badRequest(err))
 		return
 	}
 
 	expr, err := parser.NewParser(astRequest.Data.Query).ParseStmts()
 	if err != nil {
-		wrapError(rw, err)
+		wrapError(rw, parserError(astRequest.Data.Query, err))
 		return
 
 	}
 
 	body, err := sqlds.GetMacroCTEs(expr)
 	if err != nil {
-		wrapError(rw, err)
+		wrapError(rw, invalidQuery(err))
 		return
 
 	}
 
-	rw.WriteHeader(http.StatusOK)
-	marshal, err := json.Marshal(Response[[]sqlds.CTE]{
-		false,
-		"",
-		slices.Collect(maps.Values(body)),
-	})
-	_, err = rw.Write(marshal)
-
+	writeResponse(rw, slices.Collect(maps.Values(body)))
 }
 
-func wrapError(rw http.ResponseWriter, err error) {
-	rw.WriteHeader(http.StatusOK)
-	marshal, _ := json.Marshal(Response[any]{
-		true,
-		err.Error(),
-		nil,
-	})
-	_, err = rw.Write(marshal)
-	return
-}
-
-func Routes(ds *sqlds.HydrolixDatasource) map[string]func(http.ResponseWriter, *http.Request) {
+// Routes returns the resource routes of a datasource instance, db returns the connection pool of requests and
+// schemaCacheTTL is the lifetime of cached schema resources.
+func Routes(ds *sqlds.HydrolixDatasource, db DBFunc, schemaCacheTTL time.Duration) map[string]func(http.ResponseWriter, *http.Request) {
+	schema := NewSchema(db, schemaCacheTTL)
 	return map[string]func(http.ResponseWriter, *http.Request){
 		"/ast": AST,
 		"/interpolate": func(writer http.ResponseWriter, request *http.Request) {
 			Interpolate(ds, writer, request)
 		},
-		"/macroCTE": MacroCTEs,
+		"/explain": func(writer http.ResponseWriter, request *http.Request) {
+			Explain(ds, db, writer, request)
+		},
+		"/format":           Format,
+		"/macroCTE":         MacroCTEs,
+		"/schema/databases": schema.Databases,
+		"/schema/tables":    schema.Tables,
+		"/schema/columns":   schema.Columns,
+		"/validate": func(writer http.ResponseWriter, request *http.Request) {
+			Validate(schema, writer, request)
+		},
 	}
 }
 
@@ -175,3 +149,15 @@ type Response[T any] struct {
 	ErrorMessage string `json:"errorMessage"`
 	Data         T      `json:"data"`
 }
+
+// writeResponse writes the successful response of a route, data which doesn't marshal is an internal error
+func writeResponse[T any](rw http.ResponseWriter, data T) {
+	marshal, err := json.Marshal(Response[T]{Data: data})
+	if err != nil {
+		wrapError(rw, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	_, _ = rw.Write(marshal)
+}
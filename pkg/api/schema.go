@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schema queries of Hydrolix tables, listed for the editor's metadata provider
+const (
+	databasesSQL = "SELECT DISTINCT database FROM system.tables WHERE engine = 'TurbineStorage' " +
+		"AND database NOT IN ('sample_project', 'hdx') AND total_rows > 0 ORDER BY database"
+	tablesSQL = "SELECT name FROM system.tables WHERE engine = 'TurbineStorage' AND database = ? " +
+		"AND total_rows > 0 ORDER BY name"
+	columnsSQL = "SELECT name, type FROM system.columns WHERE database = ? AND table = ? ORDER BY position"
+	// tableRowsSQL reads the size of a table for query validation
+	tableRowsSQL = "SELECT toString(ifNull(total_rows, 0)) FROM system.tables WHERE database = ? AND name = ?"
+)
+
+// DBFunc returns the connection pool of a resource request, the pool of the datasource or, for forwarded OAuth
+// identities, the pool of the user of the request headers.
+type DBFunc func(ctx context.Context, header http.Header) (*sql.DB, error)
+
+// Schema serves databases, tables and columns of a datasource. Query results are cached per connection pool, so users
+// of forwarded OAuth identities never share results, and expire after the TTL.
+type Schema struct {
+	db  DBFunc
+	ttl time.Duration
+	now func() time.Time
+
+	mu    sync.Mutex
+	cache map[schemaKey]schemaEntry
+}
+
+type schemaKey struct {
+	db    *sql.DB
+	query string
+	args  string
+}
+
+type schemaEntry struct {
+	rows      [][]string
+	expiresAt time.Time
+}
+
+// NewSchema creates the schema of a datasource instance, results are cached for ttl, 0 disables the cache.
+func NewSchema(db DBFunc, ttl time.Duration) *Schema {
+	return &Schema{db: db, ttl: ttl, now: time.Now, cache: map[schemaKey]schemaEntry{}}
+}
+
+type SchemaData struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+}
+
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Databases lists the databases of Hydrolix tables
+func (s *Schema) Databases(rw http.ResponseWriter, req *http.Request) {
+	s.serve(rw, req, func(ctx context.Context, db *sql.DB, _ SchemaData) (any, error) {
+		rows, err := s.query(ctx, db, databasesSQL)
+		return firstColumn(rows), err
+	})
+}
+
+// Tables lists the Hydrolix tables of a database
+func (s *Schema) Tables(rw http.ResponseWriter, req *http.Request) {
+	s.serve(rw, req, func(ctx context.Context, db *sql.DB, data SchemaData) (any, error) {
+		if data.Database == "" {
+			return nil, badRequest(errors.New("database is required"))
+		}
+		rows, err := s.query(ctx, db, tablesSQL, data.Database)
+		return firstColumn(rows), err
+	})
+}
+
+// Columns lists names and types of the columns of a table
+func (s *Schema) Columns(rw http.ResponseWriter, req *http.Request) {
+	s.serve(rw, req, func(ctx context.Context, db *sql.DB, data SchemaData) (any, error) {
+		if data.Database == "" || data.Table == "" {
+			return nil, badRequest(errors.New("database and table are required"))
+		}
+		rows, err := s.query(ctx, db, columnsSQL, data.Database, data.Table)
+		if err != nil {
+			return nil, err
+		}
+		columns := make([]Column, len(rows))
+		for i, row := range rows {
+			columns[i] = Column{Name: row[0], Type: row[1]}
+		}
+		return columns, nil
+	})
+}
+
+func (s *Schema) serve(rw http.ResponseWriter, req *http.Request,
+	list func(ctx context.Context, db *sql.DB, data SchemaData) (any, error)) {
+	defer recoverPanic(rw, req)
+	var request Request[SchemaData]
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		wrapError(rw, badRequest(err))
+		return
+	}
+	db, err := s.db(req.Context(), req.Header)
+	if err != nil {
+		wrapError(rw, databaseError(err))
+		return
+	}
+	body, err := list(req.Context(), db, request.Data)
+	if err != nil {
+		wrapError(rw, err)
+		return
+	}
+
+	writeResponse(rw, body)
+}
+
+// query returns the rows of a schema query as strings, from the cache when they haven't expired. Errors are database
+// errors.
+func (s *Schema) query(ctx context.Context, db *sql.DB, query string, args ...string) ([][]string, error) {
+	key := schemaKey{db: db, query: query, args: strings.Join(args, "\x00")}
+	if rows, ok := s.cached(key); ok {
+		return rows, nil
+	}
+
+	queryArgs := make([]any, len(args))
+	for i, arg := range args {
+		queryArgs[i] = arg
+	}
+	result, err := db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	defer result.Close()
+	columns, err := result.Columns()
+	if err != nil {
+		return nil, databaseError(err)
+	}
+	rows := [][]string{}
+	for result.Next() {
+		row := make([]string, len(columns))
+		dest := make([]any, len(columns))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := result.Scan(dest...); err != nil {
+			return nil, databaseError(err)
+		}
+		rows = append(rows, row)
+	}
+	if err := result.Err(); err != nil {
+		return nil, databaseError(err)
+	}
+
+	s.store(key, rows)
+	return rows, nil
+}
+
+func (s *Schema) cached(key schemaKey) ([][]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.cache[key]
+	if !ok || !s.now().Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.rows, true
+}
+
+// store caches rows and evicts expired entries, so results of closed connection pools don't pile up.
+func (s *Schema) store(key schemaKey, rows [][]string) {
+	if s.ttl <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for k, entry := range s.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(s.cache, k)
+		}
+	}
+	s.cache[key] = schemaEntry{rows: rows, expiresAt: now.Add(s.ttl)}
+}
+
+func firstColumn(rows [][]string) []string {
+	values := make([]string, len(rows))
+	for i, row := range rows {
+		values[i] = row[0]
+	}
+	return values
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDriver is a database/sql connector answering queries with the rows of respond and recording queries
+type testDriver struct {
+	respond func(query string) *testRows
+	queries []string
+	args    [][]driver.NamedValue
+}
+
+func (d *testDriver) Connect(context.Context) (driver.Conn, error) { return d, nil }
+func (d *testDriver) Driver() driver.Driver                        { return d }
+func (d *testDriver) Open(string) (driver.Conn, error)             { return d, nil }
+func (d *testDriver) Prepare(string) (driver.Stmt, error)          { return nil, driver.ErrSkip }
+func (d *testDriver) Close() error                                 { return nil }
+func (d *testDriver) Begin() (driver.Tx, error)                    { return nil, driver.ErrSkip }
+func (d *testDriver) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	d.queries = append(d.queries, query)
+	d.args = append(d.args, args)
+	return d.respond(query), nil
+}
+
+type testRows struct {
+	columns []string
+	values  [][]driver.Value
+	row     int
+}
+
+func (r *testRows) Columns() []string { return r.columns }
+func (r *testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if r.row == len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.row])
+	r.row++
+	return nil
+}
+
+func openTestDB(t *testing.T, respond func(query string) *testRows) (*sql.DB, *testDriver) {
+	d := &testDriver{respond: respond}
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { _ = db.Close() })
+	return db, d
+}
+
+func newTestSchema(t *testing.T, ttl time.Duration) (*Schema, *testDriver) {
+	db, d := openTestDB(t, func(query string) *testRows {
+		if query == columnsSQL {
+			return &testRows{columns: []string{"name", "type"}, values: [][]driver.Value{{"ts", "DateTime"}, {"msg", "String"}}}
+		}
+		return &testRows{columns: []string{"name"}, values: [][]driver.Value{{"a"}, {"b"}}}
+	})
+	return NewSchema(func(context.Context, http.Header) (*sql.DB, error) { return db, nil }, ttl), d
+}
+
+func serveSchema[T any](t *testing.T, handler http.HandlerFunc, body string) Response[T] {
+	rw := httptest.NewRecorder()
+	handler(rw, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rw.Code)
+	var res Response[T]
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &res))
+	return res
+}
+
+func serveError(t *testing.T, handler http.HandlerFunc, body string, status int) ErrorResponse {
+	rw := httptest.NewRecorder()
+	handler(rw, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	require.Equal(t, status, rw.Code)
+	return errorResponse(t, rw)
+}
+
+func TestSchemaResources(t *testing.T) {
+	schema, d := newTestSchema(t, time.Minute)
+
+	databases := serveSchema[[]string](t, schema.Databases, ``)
+	assert.False(t, databases.Error)
+	assert.Equal(t, []string{"a", "b"}, databases.Data)
+
+	tables := serveSchema[[]string](t, schema.Tables, `{"data": {"database": "logs"}}`)
+	assert.False(t, tables.Error)
+	assert.Equal(t, []string{"a", "b"}, tables.Data)
+
+	columns := serveSchema[[]Column](t, schema.Columns, `{"data": {"database": "logs", "table": "access"}}`)
+	assert.False(t, columns.Error)
+	assert.Equal(t, []Column{{"ts", "DateTime"}, {"msg", "String"}}, columns.Data)
+
+	assert.Equal(t, []string{databasesSQL, tablesSQL, columnsSQL}, d.queries)
+	assert.Equal(t, "logs", d.args[2][0].Value)
+	assert.Equal(t, "access", d.args[2][1].Value)
+}
+
+func TestSchemaRequiredParameters(t *testing.T) {
+	schema, d := newTestSchema(t, time.Minute)
+
+	tables := serveError(t, schema.Tables, `{"data": {}}`, http.StatusBadRequest)
+	assert.True(t, tables.Error)
+	assert.Equal(t, "database is required", tables.ErrorMessage)
+	assert.Equal(t, CodeBadRequest, tables.ErrorCode)
+
+	columns := serveError(t, schema.Columns, `{"data": {"database": "logs"}}`, http.StatusBadRequest)
+	assert.True(t, columns.Error)
+	assert.Equal(t, "database and table are required", columns.ErrorMessage)
+	assert.Equal(t, CodeBadRequest, columns.ErrorCode)
+
+	assert.Empty(t, d.queries)
+}
+
+func TestSchemaCache(t *testing.T) {
+	schema, d := newTestSchema(t, time.Minute)
+	now := time.Now()
+	schema.now = func() time.Time { return now }
+
+	serveSchema[[]string](t, schema.Tables, `{"data": {"database": "logs"}}`)
+	serveSchema[[]string](t, schema.Tables, `{"data": {"database": "logs"}}`)
+	assert.Len(t, d.queries, 1)
+
+	serveSchema[[]string](t, schema.Tables, `{"data": {"database": "metrics"}}`)
+	assert.Len(t, d.queries, 2)
+
+	now = now.Add(time.Minute)
+	serveSchema[[]string](t, schema.Tables, `{"data": {"database": "logs"}}`)
+	assert.Len(t, d.queries, 3)
+	assert.Len(t, schema.cache, 1)
+}
+
+func TestSchemaCacheDisabled(t *testing.T) {
+	schema, d := newTestSchema(t, 0)
+
+	serveSchema[[]string](t, schema.Databases, `{}`)
+	serveSchema[[]string](t, schema.Databases, `{}`)
+	assert.Len(t, d.queries, 2)
+}
@@ -0,0 +1,351 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/hydrolix/clickhouse-sql-parser/parser"
+	"github.com/hydrolix/plugin/pkg/macros"
+)
+
+// Severities of diagnostics
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// largeTableRows is the number of rows from which reading a table without a time filter is reported
+const largeTableRows = 10_000_000
+
+// timeFilterMacros are the macros filtering a query by the dashboard time range
+var timeFilterMacros = []string{"timeFilter", "timeFilter_ms", "dateFilter", "dateTimeFilter", "dt"}
+
+// parserErrorRegex matches the position prefix of parser errors, 0-based line and column
+var parserErrorRegex = regexp.MustCompile(`^line (\d+):(\d+) ([^\n]*)`)
+
+// macroRegex matches macros with their escaping dollar signs
+var macroRegex = regexp.MustCompile(`\$+__(\w+)`)
+
+// Diagnostic is a problem of a query. Offsets are UTF-16 code units of the query, like JavaScript string indices,
+// lines and columns are 1-based like positions of the Monaco editor.
+type Diagnostic struct {
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Fix       *Fix   `json:"fix,omitempty"`
+}
+
+// Fix is a suggested fix of a diagnostic, replacing the query between Start and End offsets by Text
+type Fix struct {
+	Title string `json:"title"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// Validate reports parser errors, unknown macros, unknown columns and large tables read without a time filter of
+// the raw SQL of a query. Columns and table sizes are read through the schema cache, when the database is unavailable
+// only parser errors and unknown macros are reported.
+func Validate(schema *Schema, rw http.ResponseWriter, req *http.Request) {
+	defer recoverPanic(rw, req)
+	var request Request[ASTData]
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		wrapError(rw, badRequest(err))
+		return
+	}
+
+	writeResponse(rw, schema.validate(req.Context(), req.Header, request.Data.Query))
+}
+
+// issue is a diagnostic with byte offsets, converted to editor positions once all checks ran
+type issue struct {
+	severity   string
+	message    string
+	start, end int
+	fix        *fixIssue
+}
+
+type fixIssue struct {
+	title      string
+	start, end int
+	text       string
+}
+
+func (s *Schema) validate(ctx context.Context, header http.Header, query string) []Diagnostic {
+	issues := macroIssues(query)
+	stmts, err := parser.NewParser(query).ParseStmts()
+	if err != nil {
+		issues = append(issues, parserIssue(query, err))
+	} else if schemaIssues, err := s.schemaIssues(ctx, header, query, newReferences(stmts)); err != nil {
+		log.DefaultLogger.Warn("query validation without schema", "err", err)
+	} else {
+		issues = append(issues, schemaIssues...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].start < issues[j].start })
+	return diagnostics(query, issues)
+}
+
+// schemaIssues reports unknown columns and large tables read without time filter, checked against the schema
+func (s *Schema) schemaIssues(ctx context.Context, header http.Header, query string, refs *references) ([]issue, error) {
+	db, err := s.db(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	var issues []issue
+	if refs.single() {
+		columnIssues, err := s.columnIssues(ctx, db, refs)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, columnIssues...)
+	}
+	timeFilterIssues, err := s.timeFilterIssues(ctx, db, query, refs.tables)
+	if err != nil {
+		return nil, err
+	}
+	return append(issues, timeFilterIssues...), nil
+}
+
+// parserIssue locates a parser error by its line:column prefix and spans the token at the error position. Errors
+// without position span the whole query.
+func parserIssue(query string, err error) issue {
+	m := parserErrorRegex.FindStringSubmatch(err.Error())
+	if m == nil {
+		return issue{severity: SeverityError, message: err.Error(), start: 0, end: len(query)}
+	}
+	line, _ := strconv.Atoi(m[1])
+	column, _ := strconv.Atoi(m[2])
+	start := 0
+	for ; line > 0; line-- {
+		i := strings.IndexByte(query[start:], '\n')
+		if i < 0 {
+			break
+		}
+		start += i + 1
+	}
+	start = min(start+column, len(query))
+	end := start
+	for end < len(query) && !unicode.IsSpace(rune(query[end])) {
+		end++
+	}
+	if end == start && start > 0 {
+		// errors at the end of the query span the last character
+		_, size := utf8.DecodeLastRuneInString(query[:start])
+		start -= size
+	}
+	return issue{severity: SeverityError, message: m[3], start: start, end: end}
+}
+
+// macroIssues reports macros which are not supported, matching them like the sqlds interpolator: macros escaped by
+// an extra dollar sign are kept as is.
+func macroIssues(query string) []issue {
+	names := make([]string, 0, len(macros.Macros))
+	for name := range macros.Macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var issues []issue
+	for _, m := range macroRegex.FindAllStringSubmatchIndex(query, -1) {
+		start, end := m[0], m[1]
+		name := query[m[2]:m[3]]
+		if query[start+1] == '$' {
+			continue
+		}
+		if _, ok := macros.Macros[name]; ok {
+			continue
+		}
+		i := issue{severity: SeverityError, message: fmt.Sprintf("unknown macro $__%s", name), start: start, end: end}
+		if closest := closestName(name, names); closest != "" {
+			i.fix = &fixIssue{title: "Replace with $__" + closest, start: start, end: end, text: "$__" + closest}
+		}
+		issues = append(issues, i)
+	}
+	return issues
+}
+
+// columnIssues reports identifiers which are neither columns of the table nor names defined by the query. Tables
+// unknown to the schema aren't checked.
+func (s *Schema) columnIssues(ctx context.Context, db *sql.DB, refs *references) ([]issue, error) {
+	ref := refs.tables[0]
+	rows, err := s.query(ctx, db, columnsSQL, ref.database, ref.table)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	columns := firstColumn(rows)
+	known := map[string]bool{}
+	for _, column := range columns {
+		known[column] = true
+	}
+
+	var issues []issue
+	for _, ident := range refs.columns {
+		name := ident.Name
+		if known[name] || refs.defined[name] {
+			continue
+		}
+		start, end := int(ident.Pos()), int(ident.End())
+		i := issue{
+			severity: SeverityWarning,
+			message:  fmt.Sprintf("unknown column %s of table %s.%s", name, ref.database, ref.table),
+			start:    start,
+			end:      end,
+		}
+		if closest := closestName(name, columns); closest != "" {
+			i.fix = &fixIssue{title: "Replace with " + closest, start: start, end: end, text: closest}
+		}
+		issues = append(issues, i)
+	}
+	return issues, nil
+}
+
+// timeFilterIssues reports large tables read by a query without time filter macros, the fix adds $__timeFilter(),
+// which filters by the primary key, to the WHERE clause of the SELECT reading the table.
+func (s *Schema) timeFilterIssues(ctx context.Context, db *sql.DB, query string, refs []tableRef) ([]issue, error) {
+	for _, m := range macroRegex.FindAllStringSubmatchIndex(query, -1) {
+		if query[m[0]+1] != '$' && slices.Contains(timeFilterMacros, query[m[2]:m[3]]) {
+			return nil, nil
+		}
+	}
+
+	var issues []issue
+	for _, ref := range refs {
+		rows, err := s.query(ctx, db, tableRowsSQL, ref.database, ref.table)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		total, _ := strconv.ParseUint(rows[0][0], 10, 64)
+		if total < largeTableRows {
+			continue
+		}
+		issues = append(issues, issue{
+			severity: SeverityWarning,
+			message: fmt.Sprintf("table %s.%s has %d rows and is read without $__timeFilter, the query scans it "+
+				"regardless of the dashboard time range", ref.database, ref.table, total),
+			start: ref.start,
+			end:   ref.end,
+			fix: &fixIssue{
+				title: "Filter by the dashboard time range with $__timeFilter()",
+				start: ref.filterAt,
+				end:   ref.filterAt,
+				text:  ref.filterText,
+			},
+		})
+	}
+	return issues, nil
+}
+
+// diagnostics converts byte offsets of issues to editor positions
+func diagnostics(query string, issues []issue) []Diagnostic {
+	positions := newPositions(query)
+	result := make([]Diagnostic, len(issues))
+	for i, is := range issues {
+		start, end := positions.at(is.start), positions.at(is.end)
+		d := Diagnostic{
+			Severity:  is.severity,
+			Message:   is.message,
+			Start:     start.offset,
+			End:       end.offset,
+			Line:      start.line,
+			Column:    start.column,
+			EndLine:   end.line,
+			EndColumn: end.column,
+		}
+		if is.fix != nil {
+			d.Fix = &Fix{
+				Title: is.fix.title,
+				Start: positions.at(is.fix.start).offset,
+				End:   positions.at(is.fix.end).offset,
+				Text:  is.fix.text,
+			}
+		}
+		result[i] = d
+	}
+	return result
+}
+
+type position struct {
+	offset, line, column int
+}
+
+// positions maps byte offsets of a query to UTF-16 offsets and 1-based lines and columns
+type positions []position
+
+func newPositions(query string) positions {
+	p := make(positions, len(query)+1)
+	current := position{line: 1, column: 1}
+	for i, r := range query {
+		p[i] = current
+		n := utf16.RuneLen(r)
+		current.offset += n
+		if r == '\n' {
+			current.line++
+			current.column = 1
+		} else {
+			current.column += n
+		}
+	}
+	p[len(query)] = current
+	return p
+}
+
+func (p positions) at(offset int) position {
+	// offsets inside a multibyte character resolve to the character
+	for offset > 0 && p[offset] == (position{}) {
+		offset--
+	}
+	return p[offset]
+}
+
+// closestName returns the name nearest to name by edit distance, at most a third of its length, or "" if none is
+func closestName(name string, names []string) string {
+	closest, best := "", len([]rune(name))/3+1
+	for _, n := range names {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(n)); d < best {
+			closest, best = n, d
+		}
+	}
+	return closest
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
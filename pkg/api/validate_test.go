@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newValidateSchema(t *testing.T, rows string) *Schema {
+	db, _ := openTestDB(t, func(query string) *testRows {
+		switch query {
+		case columnsSQL:
+			return &testRows{columns: []string{"name", "type"}, values: [][]driver.Value{
+				{"timestamp", "DateTime"}, {"status", "UInt16"}, {"message", "String"},
+			}}
+		case tableRowsSQL:
+			return &testRows{columns: []string{"total_rows"}, values: [][]driver.Value{{rows}}}
+		}
+		return &testRows{}
+	})
+	return NewSchema(func(context.Context, http.Header) (*sql.DB, error) { return db, nil }, time.Minute)
+}
+
+func validateQuery(schema *Schema, query string) []Diagnostic {
+	return schema.validate(context.Background(), nil, query)
+}
+
+func TestValidateColumns(t *testing.T) {
+	schema := newValidateSchema(t, "10")
+
+	diagnostics := validateQuery(schema, "SELECT $__timeInterval(timestamp) AS time, count() c, stauts\n"+
+		"FROM logs.access\n"+
+		"WHERE $__timeFilter(timestamp) AND message ILIKE '%error%' AND arrayExists(x -> x > 1, [status])\n"+
+		"GROUP BY time ORDER BY c DESC SETTINGS max_threads = 1")
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, Diagnostic{
+		Severity:  SeverityWarning,
+		Message:   "unknown column stauts of table logs.access",
+		Start:     54,
+		End:       60,
+		Line:      1,
+		Column:    55,
+		EndLine:   1,
+		EndColumn: 61,
+		Fix:       &Fix{Title: "Replace with status", Start: 54, End: 60, Text: "status"},
+	}, diagnostics[0])
+
+	assert.Empty(t, validateQuery(schema, "SELECT `status` FROM logs.access WHERE $__timeFilter()"))
+	// identifiers of queries reading several tables aren't resolved
+	assert.Empty(t, validateQuery(schema,
+		"SELECT unknown FROM logs.access a JOIN logs.errors e ON a.id = e.id WHERE $__timeFilter()"))
+}
+
+func TestValidateMacros(t *testing.T) {
+	schema := newValidateSchema(t, "10")
+
+	diagnostics := validateQuery(schema, "SELECT $$__escaped, 'é' FROM logs.access WHERE $__timeFiltr(timestamp)")
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, SeverityError, diagnostics[0].Severity)
+	assert.Equal(t, "unknown macro $__timeFiltr", diagnostics[0].Message)
+	// é is a single UTF-16 code unit
+	assert.Equal(t, 47, diagnostics[0].Start)
+	assert.Equal(t, 59, diagnostics[0].End)
+	assert.Equal(t, &Fix{Title: "Replace with $__timeFilter", Start: 47, End: 59, Text: "$__timeFilter"},
+		diagnostics[0].Fix)
+}
+
+func TestValidateTimeFilter(t *testing.T) {
+	large := newValidateSchema(t, "20000000")
+
+	for query, fix := range map[string]Fix{
+		"SELECT count() FROM logs.access":                                 {Start: 31, End: 31, Text: " WHERE $__timeFilter()"},
+		"SELECT count() FROM logs.access WHERE status = 500":              {Start: 37, End: 37, Text: " $__timeFilter() AND"},
+		"SELECT status FROM logs.access GROUP BY status;":                 {Start: 30, End: 30, Text: " WHERE $__timeFilter()"},
+		"SELECT * FROM (SELECT status FROM logs.access) LIMIT 10":         {Start: 45, End: 45, Text: " WHERE $__timeFilter()"},
+		"SELECT count() FROM logs.access WHERE $$__timeFilter(timestamp)": {Start: 37, End: 37, Text: " $__timeFilter() AND"},
+	} {
+		diagnostics := validateQuery(large, query)
+		require.Len(t, diagnostics, 1, query)
+		assert.Equal(t, SeverityWarning, diagnostics[0].Severity)
+		assert.Equal(t, "table logs.access has 20000000 rows and is read without $__timeFilter, the query scans it "+
+			"regardless of the dashboard time range", diagnostics[0].Message)
+		fix.Title = "Filter by the dashboard time range with $__timeFilter()"
+		assert.Equal(t, &fix, diagnostics[0].Fix, query)
+	}
+
+	assert.Empty(t, validateQuery(large, "SELECT count() FROM logs.access WHERE $__dateFilter(timestamp)"))
+	assert.Empty(t, validateQuery(newValidateSchema(t, "10"), "SELECT count() FROM logs.access"))
+}
+
+func TestValidateUnterminatedIdentifiers(t *testing.T) {
+	schema := newValidateSchema(t, "10")
+
+	for _, query := range []string{
+		"SELECT * FROM db.`",
+		"SELECT a FROM db.t WHERE `",
+		`SELECT a FROM db.t WHERE "`,
+	} {
+		assert.NotPanics(t, func() { validateQuery(schema, query) }, query)
+	}
+	assert.Empty(t, validateQuery(schema, "SELECT `status`, \"message\" FROM logs.access WHERE $__timeFilter()"))
+}
+
+func TestValidateWithoutDatabase(t *testing.T) {
+	schema := NewSchema(func(context.Context, http.Header) (*sql.DB, error) {
+		return nil, errors.New("connection refused")
+	}, time.Minute)
+
+	// parser errors and macros are checked without database
+	rw := httptest.NewRecorder()
+	Validate(schema, rw, httptest.NewRequest(http.MethodPost, "/validate",
+		strings.NewReader(`{"data": {"query": "SELECT stauts FROM logs.access WHERE $__timeFiltr()"}}`)))
+	require.Equal(t, http.StatusOK, rw.Code)
+	var res Response[[]Diagnostic]
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &res))
+	require.Len(t, res.Data, 1)
+	assert.Equal(t, "unknown macro $__timeFiltr", res.Data[0].Message)
+}
+
+func TestParserIssue(t *testing.T) {
+	query := "SELECT *\nFROM logs.access\nWHERE status =="
+	i := parserIssue(query, errors.New("line 2:13 expected expression\nWHERE status ==\n             ^"))
+	assert.Equal(t, "expected expression", i.message)
+	assert.Equal(t, "==", query[i.start:i.end])
+
+	i = parserIssue(query, errors.New("line 2:15 unexpected end of input"))
+	assert.Equal(t, "=", query[i.start:i.end])
+
+	i = parserIssue(query, errors.New("unexpected error"))
+	assert.Equal(t, query, query[i.start:i.end])
+}
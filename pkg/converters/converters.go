@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"reflect"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/data"
@@ -28,7 +29,7 @@ func (c *Converter) toSqlConverter(name string) sqlutil.Converter {
 	return sqlutil.Converter{
 		Name:           name,
 		InputScanType:  c.scanType,
-		InputTypeRegex: c.matchRegex,
+		InputTypeRegex: lowCardinalityRegex(name, c.matchRegex),
 		InputTypeName:  name,
 		FrameConverter: sqlutil.FrameConverter{
 			FieldType:     c.fieldType,
@@ -37,6 +38,25 @@ func (c *Converter) toSqlConverter(name string) sqlutil.Converter {
 	}
 }
 
+// lowCardinalityRegex extends the type matched by a converter to its LowCardinality variant. LowCardinality columns
+// are scanned like their inner type, so the converter of the inner type applies as is.
+func lowCardinalityRegex(name string, matchRegex *regexp.Regexp) *regexp.Regexp {
+	inner := regexp.QuoteMeta(name)
+	if matchRegex != nil {
+		expr := matchRegex.String()
+		if !strings.HasPrefix(expr, "^") {
+			return matchRegex
+		}
+		trimmed, ok := strings.CutSuffix(strings.TrimPrefix(expr, "^"), "$")
+		if !ok {
+			// the regex matches a prefix of the type, the closing parenthesis is part of the unmatched suffix
+			return regexp.MustCompile(`^(?:LowCardinality\()?(?:` + trimmed + `)`)
+		}
+		inner = trimmed
+	}
+	return regexp.MustCompile(`^(?:` + inner + `|LowCardinality\((?:` + inner + `)\))$`)
+}
+
 // Default converter transforms nullables to their type and empty nullables as string nullables.
 func defaultConvert(in interface{}) (interface{}, error) {
 	if in == nil {
@@ -85,6 +105,14 @@ var convertersMap = map[string]Converter{
 		fieldType: data.FieldTypeNullableBool,
 		scanType:  reflect.PointerTo(reflect.PointerTo(reflect.TypeOf(true))),
 	},
+	"Float32": {
+		fieldType: data.FieldTypeFloat32,
+		scanType:  reflect.PointerTo(reflect.TypeOf(float32(0))),
+	},
+	"Nullable(Float32)": {
+		fieldType: data.FieldTypeNullableFloat32,
+		scanType:  reflect.PointerTo(reflect.PointerTo(reflect.TypeOf(float32(0)))),
+	},
 	"Float64": {
 		fieldType: data.FieldTypeFloat64,
 		scanType:  reflect.PointerTo(reflect.TypeOf(float64(0))),
@@ -157,14 +185,14 @@ var convertersMap = map[string]Converter{
 		fieldType: data.FieldTypeNullableInt8,
 		scanType:  reflect.PointerTo(reflect.PointerTo(reflect.TypeOf(int8(0)))),
 	},
-	// covers DateTime with tz, DateTime64 - see regexes, Date32
+	// covers Date32, DateTime and DateTime64 are converted by timeConverters
 	"Date": {
-		matchRegex: regexp.MustCompile(`^Date\(?`),
+		matchRegex: regexp.MustCompile(`^Date(32)?$`),
 		fieldType:  data.FieldTypeTime,
 		scanType:   reflect.PointerTo(reflect.TypeOf(time.Time{})),
 	},
 	"Nullable(Date)": {
-		matchRegex: regexp.MustCompile(`^Nullable\(Date\(?`),
+		matchRegex: regexp.MustCompile(`^Nullable\(Date(32)?\)$`),
 		fieldType:  data.FieldTypeNullableTime,
 		scanType:   reflect.PointerTo(reflect.PointerTo(reflect.TypeOf(time.Time{}))),
 	},
@@ -187,11 +215,48 @@ var convertersMap = map[string]Converter{
 	},
 }
 
-// Converters List of adapters for Grafana data.Frame
-var Converters = func() []sqlutil.Converter {
-	var list = make([]sqlutil.Converter, 0, len(convertersMap))
-	for name, converter := range convertersMap {
+// Options changes how column types without an exact Grafana field type are converted.
+type Options struct {
+	// DecimalAsString converts decimals to strings keeping every digit instead of float64.
+	DecimalAsString bool
+	// WideIntegersAsFloat converts 128 and 256-bit integers to float64 instead of strings, rounding values above 2^53.
+	WideIntegersAsFloat bool
+	// TypeMappings override the converters of the column types they match, the first matching mapping applies.
+	// Mappings must be valid, see TypeMapping.Validate.
+	TypeMappings []TypeMapping
+}
+
+// New returns the list of adapters for Grafana data.Frame configured by opts.
+func New(opts Options) []sqlutil.Converter {
+	converters := map[string]Converter{}
+	for _, m := range []map[string]Converter{
+		convertersMap,
+		semiStructuredConverters,
+		geoConverters,
+		timeConverters(),
+		decimalConverters(opts.DecimalAsString),
+		wideIntegerConverters(opts.WideIntegersAsFloat),
+		textConverters("IPv4", `IPv4`, identity),
+		textConverters("IPv6", `IPv6`, identity),
+		textConverters("UUID", `UUID`, identity),
+		textConverters("FixedString()", `FixedString\(\d+\)`, trimFixedString),
+		textConverters("Enum()", `Enum(8|16)\(.*\)`, identity),
+	} {
+		for name, converter := range m {
+			converters[name] = converter
+		}
+	}
+
+	var list []sqlutil.Converter
+	for _, m := range opts.TypeMappings {
+		list = append(list, m.toSqlConverter())
+	}
+	list = append(list, tupleConverters()...)
+	for name, converter := range converters {
 		list = append(list, converter.toSqlConverter(name))
 	}
 	return list
-}()
+}
+
+// Converters List of adapters for Grafana data.Frame with default options
+var Converters = New(Options{})
@@ -1,18 +1,34 @@
 package converters_test
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
-	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+	"math/big"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2/lib/chcol"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
 	"github.com/hydrolix/plugin/pkg/converters"
+	"github.com/paulmach/orb"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
 func getConverter(columnType string) sqlutil.Converter {
-	for _, c := range converters.Converters {
+	return findConverter(converters.Converters, columnType)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func findConverter(list []sqlutil.Converter, columnType string) sqlutil.Converter {
+	for _, c := range list {
 		if c.Name == columnType || (c.InputTypeRegex != nil && c.InputTypeRegex.MatchString(columnType)) {
 			return c
 		}
@@ -77,6 +93,34 @@ func TestNullableBool(t *testing.T) {
 	assert.Equal(t, value, actual)
 }
 
+func TestFloat32(t *testing.T) {
+	value := float32(0.25)
+	sut := getConverter("Float32")
+	v, err := sut.FrameConverter.ConverterFunc(&value)
+	assert.Nil(t, err)
+	actual := v.(float32)
+	assert.Equal(t, value, actual)
+}
+
+func TestNullableFloat32(t *testing.T) {
+	value := float32(0.25)
+	val := &value
+	sut := getConverter("Nullable(Float32)")
+	v, err := sut.FrameConverter.ConverterFunc(&val)
+	assert.Nil(t, err)
+	actual := v.(*float32)
+	assert.Equal(t, value, *actual)
+}
+
+func TestNullableFloat32ShouldBeNil(t *testing.T) {
+	var value *float32
+	sut := getConverter("Nullable(Float32)")
+	v, err := sut.FrameConverter.ConverterFunc(&value)
+	assert.Nil(t, err)
+	actual := v.(*float32)
+	assert.Equal(t, value, actual)
+}
+
 func TestFloat64(t *testing.T) {
 	value := 1.1
 	sut := getConverter("Float64")
@@ -286,6 +330,337 @@ func TestNullableUInt64ShouldBeNil(t *testing.T) {
 	assert.Equal(t, value, actual)
 }
 
+func TestDecimal(t *testing.T) {
+	for _, columnType := range []string{"Decimal(18, 4)", "Decimal32(2)", "Decimal64(4)", "Decimal128(10)", "Decimal256(20)"} {
+		t.Run(columnType, func(t *testing.T) {
+			value := decimal.RequireFromString("1234.5678")
+			sut := getConverter(columnType)
+			assert.Equal(t, data.FieldTypeFloat64, sut.FrameConverter.FieldType)
+			v, err := sut.FrameConverter.ConverterFunc(&value)
+			assert.Nil(t, err)
+			assert.Equal(t, 1234.5678, v.(float64))
+		})
+	}
+}
+
+func TestNullableDecimal(t *testing.T) {
+	value := decimal.RequireFromString("-0.25")
+	val := &value
+	sut := getConverter("Nullable(Decimal(9, 2))")
+	assert.Equal(t, data.FieldTypeNullableFloat64, sut.FrameConverter.FieldType)
+	v, err := sut.FrameConverter.ConverterFunc(&val)
+	assert.Nil(t, err)
+	assert.Equal(t, -0.25, *v.(*float64))
+}
+
+func TestNullableDecimalShouldBeNil(t *testing.T) {
+	var value *decimal.Decimal
+	sut := getConverter("Nullable(Decimal(9, 2))")
+	v, err := sut.FrameConverter.ConverterFunc(&value)
+	assert.Nil(t, err)
+	assert.Equal(t, (*float64)(nil), v.(*float64))
+}
+
+func TestDecimalAsString(t *testing.T) {
+	list := converters.New(converters.Options{DecimalAsString: true})
+	value := decimal.RequireFromString("12345678901234567890.123456789")
+
+	sut := findConverter(list, "Decimal(38, 9)")
+	assert.Equal(t, data.FieldTypeString, sut.FrameConverter.FieldType)
+	v, err := sut.FrameConverter.ConverterFunc(&value)
+	assert.Nil(t, err)
+	assert.Equal(t, "12345678901234567890.123456789", v.(string))
+
+	val := &value
+	sut = findConverter(list, "Nullable(Decimal128(9))")
+	assert.Equal(t, data.FieldTypeNullableString, sut.FrameConverter.FieldType)
+	v, err = sut.FrameConverter.ConverterFunc(&val)
+	assert.Nil(t, err)
+	assert.Equal(t, "12345678901234567890.123456789", *v.(*string))
+
+	var null *decimal.Decimal
+	v, err = sut.FrameConverter.ConverterFunc(&null)
+	assert.Nil(t, err)
+	assert.Equal(t, (*string)(nil), v.(*string))
+}
+
+func TestWideIntegers(t *testing.T) {
+	for _, columnType := range []string{"Int128", "Int256", "UInt128", "UInt256"} {
+		t.Run(columnType, func(t *testing.T) {
+			value, _ := new(big.Int).SetString("170141183460469231731687303715884105727", 10)
+			sut := getConverter(columnType)
+			assert.Equal(t, data.FieldTypeString, sut.FrameConverter.FieldType)
+			v, err := sut.FrameConverter.ConverterFunc(&value)
+			assert.Nil(t, err)
+			assert.Equal(t, "170141183460469231731687303715884105727", v.(string))
+		})
+	}
+}
+
+func TestNullableWideInteger(t *testing.T) {
+	value := big.NewInt(-42)
+	sut := getConverter("Nullable(Int128)")
+	assert.Equal(t, data.FieldTypeNullableString, sut.FrameConverter.FieldType)
+	v, err := sut.FrameConverter.ConverterFunc(&value)
+	assert.Nil(t, err)
+	assert.Equal(t, "-42", *v.(*string))
+
+	var null *big.Int
+	v, err = sut.FrameConverter.ConverterFunc(&null)
+	assert.Nil(t, err)
+	assert.Equal(t, (*string)(nil), v.(*string))
+}
+
+func TestWideIntegersAsFloat(t *testing.T) {
+	list := converters.New(converters.Options{WideIntegersAsFloat: true})
+
+	// 2^53 + 1 is the first integer float64 can't represent
+	value, _ := new(big.Int).SetString("9007199254740993", 10)
+	sut := findConverter(list, "UInt256")
+	assert.Equal(t, data.FieldTypeFloat64, sut.FrameConverter.FieldType)
+	v, err := sut.FrameConverter.ConverterFunc(&value)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(9007199254740992), v.(float64))
+
+	sut = findConverter(list, "Nullable(Int256)")
+	assert.Equal(t, data.FieldTypeNullableFloat64, sut.FrameConverter.FieldType)
+	var null *big.Int
+	v, err = sut.FrameConverter.ConverterFunc(&null)
+	assert.Nil(t, err)
+	assert.Equal(t, (*float64)(nil), v.(*float64))
+}
+
+func TestTextTypes(t *testing.T) {
+	stringType := reflect.PointerTo(reflect.TypeOf(""))
+	nullableStringType := reflect.PointerTo(stringType)
+	tests := []struct {
+		columnType string
+		value      string
+	}{
+		{"IPv4", "192.168.1.10"},
+		{"LowCardinality(IPv4)", "192.168.1.10"},
+		{"IPv6", "2001:db8::ff00:42:8329"},
+		{"LowCardinality(IPv6)", "2001:db8::ff00:42:8329"},
+		{"UUID", "0b5c7a3e-9f2d-4c1a-8e6b-2d4f6a8c0e1f"},
+		{"LowCardinality(UUID)", "0b5c7a3e-9f2d-4c1a-8e6b-2d4f6a8c0e1f"},
+		{"FixedString(2)", "US"},
+		{"LowCardinality(FixedString(16))", "edge-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.columnType, func(t *testing.T) {
+			sut := getConverter(tt.columnType)
+			assert.Equal(t, stringType, sut.InputScanType)
+			assert.Equal(t, data.FieldTypeString, sut.FrameConverter.FieldType)
+			value := tt.value
+			v, err := sut.FrameConverter.ConverterFunc(&value)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.value, v.(string))
+		})
+
+		nullableColumnType := "Nullable(" + tt.columnType + ")"
+		if lowCardinality, ok := strings.CutPrefix(tt.columnType, "LowCardinality("); ok {
+			nullableColumnType = "LowCardinality(Nullable(" + lowCardinality + ")"
+		}
+		t.Run(nullableColumnType, func(t *testing.T) {
+			sut := getConverter(nullableColumnType)
+			assert.Equal(t, nullableStringType, sut.InputScanType)
+			assert.Equal(t, data.FieldTypeNullableString, sut.FrameConverter.FieldType)
+			value := tt.value
+			val := &value
+			v, err := sut.FrameConverter.ConverterFunc(&val)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.value, *v.(*string))
+
+			var null *string
+			v, err = sut.FrameConverter.ConverterFunc(&null)
+			assert.Nil(t, err)
+			assert.Equal(t, (*string)(nil), v.(*string))
+		})
+	}
+}
+
+func TestLowCardinality(t *testing.T) {
+	tests := []struct {
+		columnType string
+		converter  string
+	}{
+		{"LowCardinality(String)", "String"},
+		{"LowCardinality(Nullable(String))", "Nullable(String)"},
+		{"LowCardinality(UInt16)", "UInt16"},
+		{"LowCardinality(Nullable(Int64))", "Nullable(Int64)"},
+		{"LowCardinality(Date)", "Date"},
+		{"LowCardinality(Nullable(Date))", "Nullable(Date)"},
+		{"LowCardinality(Nullable(Decimal(9, 2)))", "Nullable(Decimal())"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.columnType, func(t *testing.T) {
+			assert.Equal(t, tt.converter, getConverter(tt.columnType).Name)
+		})
+	}
+}
+
+func TestLowCardinalityString(t *testing.T) {
+	value := "edge"
+	sut := getConverter("LowCardinality(String)")
+	assert.Equal(t, data.FieldTypeString, sut.FrameConverter.FieldType)
+	v, err := sut.FrameConverter.ConverterFunc(&value)
+	assert.Nil(t, err)
+	assert.Equal(t, "edge", v.(string))
+
+	var null *string
+	sut = getConverter("LowCardinality(Nullable(String))")
+	assert.Equal(t, data.FieldTypeNullableString, sut.FrameConverter.FieldType)
+	v, err = sut.FrameConverter.ConverterFunc(&null)
+	assert.Nil(t, err)
+	assert.Equal(t, (*string)(nil), v.(*string))
+}
+
+func TestEnum(t *testing.T) {
+	for _, columnType := range []string{"Enum8('GET' = 1, 'POST' = 2)", "Enum16('GET' = 1, 'a(b)' = 300)"} {
+		t.Run(columnType, func(t *testing.T) {
+			value := "GET"
+			sut := getConverter(columnType)
+			assert.Equal(t, data.FieldTypeString, sut.FrameConverter.FieldType)
+			v, err := sut.FrameConverter.ConverterFunc(&value)
+			assert.Nil(t, err)
+			assert.Equal(t, "GET", v.(string))
+		})
+	}
+}
+
+func TestNullableEnum(t *testing.T) {
+	value := "POST"
+	val := &value
+	sut := getConverter("Nullable(Enum8('GET' = 1, 'POST' = 2))")
+	assert.Equal(t, data.FieldTypeNullableString, sut.FrameConverter.FieldType)
+	v, err := sut.FrameConverter.ConverterFunc(&val)
+	assert.Nil(t, err)
+	assert.Equal(t, "POST", *v.(*string))
+
+	var null *string
+	v, err = sut.FrameConverter.ConverterFunc(&null)
+	assert.Nil(t, err)
+	assert.Equal(t, (*string)(nil), v.(*string))
+}
+
+func TestFixedStringShouldBeTrimmed(t *testing.T) {
+	value := "edge-01\x00\x00\x00"
+	sut := getConverter("FixedString(10)")
+	v, err := sut.FrameConverter.ConverterFunc(&value)
+	assert.Nil(t, err)
+	assert.Equal(t, "edge-01", v.(string))
+
+	val := &value
+	sut = getConverter("Nullable(FixedString(10))")
+	v, err = sut.FrameConverter.ConverterFunc(&val)
+	assert.Nil(t, err)
+	assert.Equal(t, "edge-01", *v.(*string))
+}
+
+func TestTuple(t *testing.T) {
+	tests := []struct {
+		columnType string
+		converter  string
+		value      interface{}
+		want       string
+	}{
+		{
+			columnType: "Tuple(a String, b UInt8)",
+			converter:  "Tuple()",
+			value:      interface{}(map[string]interface{}{"a": "x", "b": uint8(1)}),
+			want:       `{"a":"x","b":1}`,
+		},
+		{
+			columnType: "Tuple(`status code` UInt16, `path` Nullable(String))",
+			converter:  "Tuple()",
+			value:      interface{}(map[string]interface{}{"status code": uint16(200), "path": (*string)(nil)}),
+			want:       `{"path":null,"status code":200}`,
+		},
+		{
+			columnType: "Tuple(String, DateTime64(3, 'UTC'), Array(UInt8))",
+			converter:  "Tuple(unnamed)",
+			value:      []interface{}{"x", time.Date(2025, 2, 11, 1, 1, 1, 0, time.UTC), []uint8{1, 2}},
+			want:       `["x","2025-02-11T01:01:01Z","AQI="]`,
+		},
+		{
+			columnType: "Array(Tuple(host String, hits UInt64))",
+			converter:  "Nested()",
+			value:      []map[string]interface{}{{"host": "a", "hits": uint64(1)}, {"host": "b", "hits": uint64(2)}},
+			want:       `[{"hits":1,"host":"a"},{"hits":2,"host":"b"}]`,
+		},
+		{
+			columnType: "Array(Tuple(String, UInt64))",
+			converter:  "Array(Tuple(unnamed))",
+			value:      [][]interface{}{{"a", uint64(1)}},
+			want:       `[["a",1]]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.columnType, func(t *testing.T) {
+			sut := getConverter(tt.columnType)
+			assert.Equal(t, tt.converter, sut.Name)
+			assert.Equal(t, data.FieldTypeJSON, sut.FrameConverter.FieldType)
+			in := reflect.New(sut.InputScanType)
+			in.Elem().Set(reflect.ValueOf(tt.value))
+			v, err := sut.FrameConverter.ConverterFunc(in.Interface())
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.want, string(v.(json.RawMessage)))
+		})
+	}
+}
+
+func TestArrayIsNotTuple(t *testing.T) {
+	assert.Equal(t, "Array()", getConverter("Array(String)").Name)
+	assert.Equal(t, "Array()", getConverter("Array(Array(Tuple(a String)))").Name)
+}
+
+func TestJSON(t *testing.T) {
+	for _, columnType := range []string{"JSON", "JSON(max_dynamic_paths=16, a.b UInt32)", "Object('json')"} {
+		t.Run(columnType, func(t *testing.T) {
+			sut := getConverter(columnType)
+			assert.Equal(t, "JSON", sut.Name)
+			assert.Equal(t, data.FieldTypeNullableJSON, sut.FrameConverter.FieldType)
+		})
+	}
+
+	sut := getConverter("JSON")
+	in := reflect.New(sut.InputScanType).Interface()
+	obj := chcol.NewJSON()
+	obj.SetValueAtPath("a.b", uint32(1))
+	obj.SetValueAtPath("c", chcol.NewDynamic("x"))
+	assert.Nil(t, in.(chcol.JSONDeserializer).DeserializeClickHouseJSON(obj))
+	v, err := sut.FrameConverter.ConverterFunc(in)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"a":{"b":1},"c":"x"}`, string(*v.(*json.RawMessage)))
+
+	// output_format_native_write_json_as_string
+	in = reflect.New(sut.InputScanType).Interface()
+	assert.Nil(t, in.(sql.Scanner).Scan(`{"a":{"b":1}}`))
+	v, err = sut.FrameConverter.ConverterFunc(in)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"a":{"b":1}}`, string(*v.(*json.RawMessage)))
+}
+
+func TestVariant(t *testing.T) {
+	for _, columnType := range []string{"Variant(String, UInt64)", "Dynamic", "Dynamic(max_types=8)"} {
+		t.Run(columnType, func(t *testing.T) {
+			sut := getConverter(columnType)
+			assert.Equal(t, "Variant()", sut.Name)
+			assert.Equal(t, data.FieldTypeNullableJSON, sut.FrameConverter.FieldType)
+
+			value := chcol.NewVariantWithType([]string{"a", "b"}, "Array(String)")
+			v, err := sut.FrameConverter.ConverterFunc(&value)
+			assert.Nil(t, err)
+			assert.JSONEq(t, `["a","b"]`, string(*v.(*json.RawMessage)))
+
+			null := chcol.NewVariant(nil)
+			v, err = sut.FrameConverter.ConverterFunc(&null)
+			assert.Nil(t, err)
+			assert.Equal(t, (*json.RawMessage)(nil), v.(*json.RawMessage))
+		})
+	}
+}
+
 func toJson(obj interface{}) (json.RawMessage, error) {
 	bytes, err := json.Marshal(obj)
 	if err != nil {
@@ -323,3 +698,69 @@ func TestArray(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, msg, *v.(*json.RawMessage))
 }
+
+func TestGeo(t *testing.T) {
+	ring := orb.Ring{{0, 0}, {10, 0}, {10, 10}, {0, 0}}
+	for columnType, tc := range map[string]struct {
+		value    interface{}
+		expected string
+	}{
+		"Point":        {orb.Point{-73.98, 40.75}, `{"type":"Point","coordinates":[-73.98,40.75]}`},
+		"Ring":         {ring, `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,0]]]}`},
+		"Polygon":      {orb.Polygon{ring}, `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,0]]]}`},
+		"MultiPolygon": {orb.MultiPolygon{{ring}}, `{"type":"MultiPolygon","coordinates":[[[[0,0],[10,0],[10,10],[0,0]]]]}`},
+	} {
+		t.Run(columnType, func(t *testing.T) {
+			sut := getConverter(columnType)
+			assert.Equal(t, columnType, sut.Name)
+			assert.Equal(t, data.FieldTypeNullableJSON, sut.FrameConverter.FieldType)
+			assert.Equal(t, reflect.TypeOf(tc.value), sut.InputScanType)
+
+			in := reflect.New(sut.InputScanType)
+			in.Elem().Set(reflect.ValueOf(tc.value))
+			v, err := sut.FrameConverter.ConverterFunc(in.Interface())
+			assert.Nil(t, err)
+			assert.JSONEq(t, tc.expected, string(*v.(*json.RawMessage)))
+		})
+	}
+}
+
+func TestTypeMapping(t *testing.T) {
+	list := converters.New(converters.Options{TypeMappings: []converters.TypeMapping{
+		{TypeRegex: `^(Nullable\()?UInt64\)?$`, FieldType: converters.FieldTypeString},
+		{TypeRegex: `^Map\(`, FieldType: converters.FieldTypeString, Mode: converters.ModeLabels},
+		{TypeRegex: `^(Nullable\()?String\)?$`, FieldType: converters.FieldTypeNumber},
+		{TypeRegex: `^Int128$`, FieldType: converters.FieldTypeNumber},
+		{TypeRegex: `^UInt8$`, FieldType: converters.FieldTypeBoolean},
+		{TypeRegex: `^Decimal`, FieldType: converters.FieldTypeJSON},
+	}})
+
+	convert := func(columnType string, value interface{}) interface{} {
+		sut := findConverter(list, columnType)
+		assert.True(t, strings.HasPrefix(sut.Name, "TypeMapping("), columnType)
+		v, err := sut.FrameConverter.ConverterFunc(&value)
+		assert.Nil(t, err)
+		return v
+	}
+
+	maxUInt64 := uint64(18446744073709551615)
+	assert.Equal(t, ptr("18446744073709551615"), convert("UInt64", maxUInt64))
+	assert.Equal(t, ptr("18446744073709551615"), convert("Nullable(UInt64)", &maxUInt64))
+	assert.Equal(t, (*string)(nil), convert("Nullable(UInt64)", nil))
+	assert.Equal(t, data.FieldTypeNullableString, findConverter(list, "UInt64").FrameConverter.FieldType)
+
+	assert.Equal(t, ptr("host=a, region=eu"), convert("Map(String, String)", map[string]string{"region": "eu", "host": "a"}))
+
+	assert.Equal(t, ptr(1.5), convert("String", "1.5"))
+	assert.Equal(t, ptr(1e20), convert("Int128", new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)))
+	assert.Equal(t, ptr(true), convert("UInt8", uint8(1)))
+	assert.Equal(t, json.RawMessage(`"1.25"`), *convert("Decimal(9, 2)", decimal.RequireFromString("1.25")).(*json.RawMessage))
+
+	sut := findConverter(list, "String")
+	var value interface{} = "x"
+	_, err := sut.FrameConverter.ConverterFunc(&value)
+	assert.NotNil(t, err)
+
+	// types without mapping keep their converter
+	assert.Equal(t, "Int64", findConverter(list, "Int64").Name)
+}
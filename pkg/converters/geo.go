@@ -0,0 +1,36 @@
+package converters
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// geoConverter converts a geo column scanned as T into a GeoJSON geometry. Ring columns become polygons with a
+// single ring, GeoJSON has no ring geometry.
+func geoConverter[T orb.Geometry]() Converter {
+	return Converter{
+		fieldType: data.FieldTypeNullableJSON,
+		scanType:  reflect.TypeFor[T](),
+		convert: func(in interface{}) (interface{}, error) {
+			b, err := json.Marshal(geojson.NewGeometry(*in.(*T)))
+			if err != nil {
+				return nil, err
+			}
+			msg := json.RawMessage(b)
+			return &msg, nil
+		},
+	}
+}
+
+// geoConverters converts geo columns to GeoJSON geometries, Point fields are split into latitude and longitude
+// fields by the plugin, see MutateResponse.
+var geoConverters = map[string]Converter{
+	"Point":        geoConverter[orb.Point](),
+	"Ring":         geoConverter[orb.Ring](),
+	"Polygon":      geoConverter[orb.Polygon](),
+	"MultiPolygon": geoConverter[orb.MultiPolygon](),
+}
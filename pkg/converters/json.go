@@ -0,0 +1,74 @@
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/chcol"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// jsonValue scans JSON and Object('json') columns, whether the query head sends them as objects or, with
+// output_format_native_write_json_as_string, as strings.
+type jsonValue struct {
+	raw json.RawMessage
+}
+
+// DeserializeClickHouseJSON implements chcol.JSONDeserializer
+func (v *jsonValue) DeserializeClickHouseJSON(in *chcol.JSON) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	v.raw = b
+	return nil
+}
+
+// Scan implements sql.Scanner
+func (v *jsonValue) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		v.raw = nil
+	case string:
+		v.raw = json.RawMessage(s)
+	case []byte:
+		v.raw = append(json.RawMessage(nil), s...)
+	default:
+		b, err := json.Marshal(s)
+		if err != nil {
+			return fmt.Errorf("unsupported JSON value %T: %w", src, err)
+		}
+		v.raw = b
+	}
+	return nil
+}
+
+var semiStructuredConverters = map[string]Converter{
+	"JSON": {
+		matchRegex: regexp.MustCompile(`^(JSON(\(.*\))?|Object\('json'\))$`),
+		fieldType:  data.FieldTypeNullableJSON,
+		scanType:   reflect.TypeOf(jsonValue{}),
+		convert: func(in interface{}) (interface{}, error) {
+			v := in.(*jsonValue)
+			if v.raw == nil {
+				return (*json.RawMessage)(nil), nil
+			}
+			return &v.raw, nil
+		},
+	},
+	// Dynamic is an alias of Variant in clickhouse-go
+	"Variant()": {
+		matchRegex: regexp.MustCompile(`^(Variant\(.*\)|Dynamic(\(.*\))?)$`),
+		fieldType:  data.FieldTypeNullableJSON,
+		scanType:   reflect.TypeOf(chcol.Variant{}),
+		convert: func(in interface{}) (interface{}, error) {
+			v := in.(*chcol.Variant)
+			if v.Nil() {
+				return (*json.RawMessage)(nil), nil
+			}
+			return jsonConverter(v.Any())
+		},
+	},
+}
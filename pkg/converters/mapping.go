@@ -0,0 +1,244 @@
+package converters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+	"github.com/shopspring/decimal"
+)
+
+// Field types of TypeMapping
+const (
+	FieldTypeString  = "string"
+	FieldTypeNumber  = "number"
+	FieldTypeBoolean = "boolean"
+	FieldTypeJSON    = "json"
+)
+
+// Conversion modes of TypeMapping
+const (
+	// ModeDefault converts values by their kind: numbers, text and booleans as is, other values as JSON.
+	ModeDefault = ""
+	// ModeLabels renders maps as key=value pairs, like labels of series. Only supported for the string field type.
+	ModeLabels = "labels"
+)
+
+// TypeMapping overrides the conversion of the column types matching TypeRegex, e.g. UInt64 columns converted to
+// strings to keep every digit in the browser, or Map columns rendered as labels.
+type TypeMapping struct {
+	// TypeRegex matches the ClickHouse type of columns, e.g. ^UInt64$ or ^Map\(String,
+	TypeRegex string `json:"typeRegex"`
+	// FieldType is the Grafana field type of converted values: string, number, boolean or json
+	FieldType string `json:"fieldType"`
+	// Mode changes how values are converted to the field type, ModeDefault or ModeLabels
+	Mode string `json:"mode,omitempty"`
+}
+
+// Validate checks the regex, the field type and the mode of the mapping.
+func (m TypeMapping) Validate() error {
+	if m.TypeRegex == "" {
+		return errors.New("type regex is required")
+	}
+	if _, err := regexp.Compile(m.TypeRegex); err != nil {
+		return fmt.Errorf("invalid type regex %q: %w", m.TypeRegex, err)
+	}
+	switch m.FieldType {
+	case FieldTypeString, FieldTypeNumber, FieldTypeBoolean, FieldTypeJSON:
+	default:
+		return fmt.Errorf("invalid field type %q of type regex %q", m.FieldType, m.TypeRegex)
+	}
+	switch m.Mode {
+	case ModeDefault:
+	case ModeLabels:
+		if m.FieldType != FieldTypeString {
+			return fmt.Errorf("mode %q of type regex %q requires the %s field type", m.Mode, m.TypeRegex, FieldTypeString)
+		}
+	default:
+		return fmt.Errorf("invalid mode %q of type regex %q", m.Mode, m.TypeRegex)
+	}
+	return nil
+}
+
+// toSqlConverter turns a valid mapping into a sqlutil.Converter. Columns are scanned as the values returned by
+// clickhouse-go and converted to a nullable field, the regex may match Nullable columns.
+func (m TypeMapping) toSqlConverter() sqlutil.Converter {
+	var (
+		fieldType data.FieldType
+		null      interface{}
+		convert   func(v interface{}) (interface{}, error)
+	)
+	switch m.FieldType {
+	case FieldTypeString:
+		fieldType, null = data.FieldTypeNullableString, (*string)(nil)
+		convert = func(v interface{}) (interface{}, error) {
+			if m.Mode == ModeLabels {
+				if labels, ok := labelsOf(v); ok {
+					s := labels.String()
+					return &s, nil
+				}
+			}
+			return stringOf(v)
+		}
+	case FieldTypeNumber:
+		fieldType, null = data.FieldTypeNullableFloat64, (*float64)(nil)
+		convert = numberOf
+	case FieldTypeBoolean:
+		fieldType, null = data.FieldTypeNullableBool, (*bool)(nil)
+		convert = booleanOf
+	default:
+		fieldType, null = data.FieldTypeNullableJSON, (*json.RawMessage)(nil)
+		convert = jsonConverter
+	}
+
+	return sqlutil.Converter{
+		Name:           "TypeMapping(" + m.TypeRegex + ")",
+		InputScanType:  reflect.TypeOf((*interface{})(nil)).Elem(),
+		InputTypeRegex: regexp.MustCompile(m.TypeRegex),
+		FrameConverter: sqlutil.FrameConverter{
+			FieldType: fieldType,
+			ConverterFunc: func(in interface{}) (interface{}, error) {
+				v := dereference(*in.(*interface{}))
+				if v == nil {
+					return null, nil
+				}
+				return convert(v)
+			},
+		},
+	}
+}
+
+var stringerType = reflect.TypeFor[fmt.Stringer]()
+
+// dereference returns the value pointed by pointers of nullable columns, nil for nulls. Pointers to values only
+// printable by their pointer, like *big.Int, are kept.
+func dereference(v interface{}) interface{} {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return nil
+		}
+		if val.Type().Implements(stringerType) && !val.Type().Elem().Implements(stringerType) {
+			break
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		return nil
+	}
+	return val.Interface()
+}
+
+func stringOf(v interface{}) (interface{}, error) {
+	var s string
+	switch t := v.(type) {
+	case string:
+		s = t
+	case []byte:
+		s = string(t)
+	case time.Time:
+		s = t.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		s = t.String()
+	default:
+		switch reflect.ValueOf(v).Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			s = fmt.Sprint(v)
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			s = string(b)
+		}
+	}
+	return &s, nil
+}
+
+// labelsOf returns the entries of a map as labels, ok is false for other values.
+func labelsOf(v interface{}) (data.Labels, bool) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Map {
+		return nil, false
+	}
+	labels := data.Labels{}
+	iter := val.MapRange()
+	for iter.Next() {
+		key, err := stringOf(iter.Key().Interface())
+		if err != nil {
+			return nil, false
+		}
+		value, err := stringOf(dereference(iter.Value().Interface()))
+		if err != nil {
+			return nil, false
+		}
+		labels[*key.(*string)] = *value.(*string)
+	}
+	return labels, true
+}
+
+func numberOf(v interface{}) (interface{}, error) {
+	var f float64
+	switch t := v.(type) {
+	case decimal.Decimal:
+		f = t.InexactFloat64()
+	case *big.Int:
+		f, _ = new(big.Float).SetInt(t).Float64()
+	case string:
+		var err error
+		if f, err = strconv.ParseFloat(t, 64); err != nil {
+			return nil, fmt.Errorf("cannot convert %q to number: %w", t, err)
+		}
+	case bool:
+		if t {
+			f = 1
+		}
+	default:
+		val := reflect.ValueOf(v)
+		switch {
+		case val.CanInt():
+			f = float64(val.Int())
+		case val.CanUint():
+			f = float64(val.Uint())
+		case val.CanFloat():
+			f = val.Float()
+		default:
+			return nil, fmt.Errorf("cannot convert %T to number", v)
+		}
+	}
+	return &f, nil
+}
+
+func booleanOf(v interface{}) (interface{}, error) {
+	var b bool
+	switch t := v.(type) {
+	case bool:
+		b = t
+	case string:
+		var err error
+		if b, err = strconv.ParseBool(t); err != nil {
+			return nil, fmt.Errorf("cannot convert %q to boolean: %w", t, err)
+		}
+	default:
+		val := reflect.ValueOf(v)
+		switch {
+		case val.CanInt():
+			b = val.Int() != 0
+		case val.CanUint():
+			b = val.Uint() != 0
+		case val.CanFloat():
+			b = val.Float() != 0
+		default:
+			return nil, fmt.Errorf("cannot convert %T to boolean", v)
+		}
+	}
+	return &b, nil
+}
@@ -0,0 +1,129 @@
+package converters
+
+import (
+	"math/big"
+	"reflect"
+	"regexp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+	bigIntType  = reflect.TypeOf(&big.Int{})
+)
+
+// decimalConverters converts Decimal(P,S) and Decimal32/64/128/256(S) columns to float64, or to strings keeping
+// every digit when asString is set.
+func decimalConverters(asString bool) map[string]Converter {
+	decimalRegex := regexp.MustCompile(`^Decimal(32|64|128|256)?\(`)
+	nullableDecimalRegex := regexp.MustCompile(`^Nullable\(Decimal(32|64|128|256)?\(`)
+	scanType := reflect.PointerTo(decimalType)
+	nullableScanType := reflect.PointerTo(reflect.PointerTo(decimalType))
+
+	if asString {
+		return map[string]Converter{
+			"Decimal()": {
+				matchRegex: decimalRegex,
+				fieldType:  data.FieldTypeString,
+				scanType:   scanType,
+				convert: func(in interface{}) (interface{}, error) {
+					return in.(*decimal.Decimal).String(), nil
+				},
+			},
+			"Nullable(Decimal())": {
+				matchRegex: nullableDecimalRegex,
+				fieldType:  data.FieldTypeNullableString,
+				scanType:   nullableScanType,
+				convert: func(in interface{}) (interface{}, error) {
+					d := *in.(**decimal.Decimal)
+					if d == nil {
+						return (*string)(nil), nil
+					}
+					s := d.String()
+					return &s, nil
+				},
+			},
+		}
+	}
+	return map[string]Converter{
+		"Decimal()": {
+			matchRegex: decimalRegex,
+			fieldType:  data.FieldTypeFloat64,
+			scanType:   scanType,
+			convert: func(in interface{}) (interface{}, error) {
+				return in.(*decimal.Decimal).InexactFloat64(), nil
+			},
+		},
+		"Nullable(Decimal())": {
+			matchRegex: nullableDecimalRegex,
+			fieldType:  data.FieldTypeNullableFloat64,
+			scanType:   nullableScanType,
+			convert: func(in interface{}) (interface{}, error) {
+				d := *in.(**decimal.Decimal)
+				if d == nil {
+					return (*float64)(nil), nil
+				}
+				f := d.InexactFloat64()
+				return &f, nil
+			},
+		},
+	}
+}
+
+// wideIntegerConverters converts Int128, Int256, UInt128 and UInt256 columns to their decimal string
+// representation, or to float64 when asFloat is set. Float64 represents integers exactly only up to 2^53, larger
+// values are rounded to the nearest float64 (15-17 significant digits). Both nullable and non-nullable wide integers
+// are scanned as *big.Int, which stays nil for NULL values.
+func wideIntegerConverters(asFloat bool) map[string]Converter {
+	scanType := reflect.PointerTo(bigIntType)
+	fieldType, nullableFieldType := data.FieldTypeString, data.FieldTypeNullableString
+	convert := func(i *big.Int) (interface{}, error) { return i.String(), nil }
+	convertNullable := func(i *big.Int) (interface{}, error) {
+		if i == nil {
+			return (*string)(nil), nil
+		}
+		s := i.String()
+		return &s, nil
+	}
+	if asFloat {
+		fieldType, nullableFieldType = data.FieldTypeFloat64, data.FieldTypeNullableFloat64
+		convert = func(i *big.Int) (interface{}, error) { return bigIntToFloat64(i), nil }
+		convertNullable = func(i *big.Int) (interface{}, error) {
+			if i == nil {
+				return (*float64)(nil), nil
+			}
+			f := bigIntToFloat64(i)
+			return &f, nil
+		}
+	}
+
+	converters := map[string]Converter{}
+	for _, name := range []string{"Int128", "Int256", "UInt128", "UInt256"} {
+		converters[name] = Converter{
+			fieldType: fieldType,
+			scanType:  scanType,
+			convert: func(in interface{}) (interface{}, error) {
+				i := *in.(**big.Int)
+				if i == nil {
+					i = new(big.Int)
+				}
+				return convert(i)
+			},
+		}
+		converters["Nullable("+name+")"] = Converter{
+			fieldType: nullableFieldType,
+			scanType:  scanType,
+			convert: func(in interface{}) (interface{}, error) {
+				return convertNullable(*in.(**big.Int))
+			},
+		}
+	}
+	return converters
+}
+
+func bigIntToFloat64(i *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(i).Float64()
+	return f
+}
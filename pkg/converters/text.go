@@ -0,0 +1,47 @@
+package converters
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// textConverters converts a column type and its Nullable variant to strings. IPv4, IPv6 and UUID columns are scanned
+// directly into strings, so clickhouse-go renders their canonical text form, and Enum8/Enum16 columns render the
+// name of their values. typeRegex matches the column type without Nullable.
+func textConverters(name, typeRegex string, convert func(s string) string) map[string]Converter {
+	return map[string]Converter{
+		name: {
+			matchRegex: regexp.MustCompile(`^(` + typeRegex + `)$`),
+			fieldType:  data.FieldTypeString,
+			scanType:   reflect.PointerTo(reflect.TypeOf("")),
+			convert: func(in interface{}) (interface{}, error) {
+				return convert(*in.(*string)), nil
+			},
+		},
+		"Nullable(" + name + ")": {
+			matchRegex: regexp.MustCompile(`^Nullable\((` + typeRegex + `)\)$`),
+			fieldType:  data.FieldTypeNullableString,
+			scanType:   reflect.PointerTo(reflect.PointerTo(reflect.TypeOf(""))),
+			convert: func(in interface{}) (interface{}, error) {
+				s := *in.(**string)
+				if s == nil {
+					return (*string)(nil), nil
+				}
+				v := convert(*s)
+				return &v, nil
+			},
+		},
+	}
+}
+
+func identity(s string) string {
+	return s
+}
+
+// trimFixedString removes the zero bytes padding values shorter than the FixedString(N) size.
+func trimFixedString(s string) string {
+	return strings.TrimRight(s, "\x00")
+}
@@ -0,0 +1,73 @@
+package converters
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// TimeColumn is the declared precision and timezone of a DateTime or DateTime64 column.
+type TimeColumn struct {
+	// Precision is the number of fractional second digits, 0 for DateTime.
+	Precision int
+	// Timezone is the declared timezone of the column, "" when the column has none and its values are in the
+	// timezone of the query head.
+	Timezone string
+}
+
+var (
+	dateTimeRegex   = regexp.MustCompile(`^DateTime(?:\('([^']*)'\))?$`)
+	dateTime64Regex = regexp.MustCompile(`^DateTime64\((\d)(?:, *'([^']*)')?\)$`)
+)
+
+// ParseTimeColumn returns precision and timezone of a DateTime or DateTime64 column type, false for other types.
+func ParseTimeColumn(columnType string) (TimeColumn, bool) {
+	for _, wrapper := range []string{"LowCardinality(", "Nullable("} {
+		if inner, ok := strings.CutPrefix(columnType, wrapper); ok {
+			columnType = strings.TrimSuffix(inner, ")")
+		}
+	}
+	if m := dateTimeRegex.FindStringSubmatch(columnType); m != nil {
+		return TimeColumn{Timezone: m[1]}, true
+	}
+	if m := dateTime64Regex.FindStringSubmatch(columnType); m != nil {
+		precision, _ := strconv.Atoi(m[1])
+		return TimeColumn{Precision: precision, Timezone: m[2]}, true
+	}
+	return TimeColumn{}, false
+}
+
+// timeConverters converts DateTime and DateTime64(P) columns. Values are kept as scanned: the driver decodes them at
+// the precision and in the timezone of the column.
+func timeConverters() map[string]Converter {
+	converters := map[string]Converter{}
+	add := func(name, typeRegex string) {
+		converters[name] = Converter{
+			matchRegex: regexp.MustCompile(`^` + typeRegex + `$`),
+			fieldType:  data.FieldTypeTime,
+			scanType:   reflect.PointerTo(reflect.TypeOf(time.Time{})),
+			convert: func(in interface{}) (interface{}, error) {
+				return *in.(*time.Time), nil
+			},
+		}
+		converters["Nullable("+name+")"] = Converter{
+			matchRegex: regexp.MustCompile(`^Nullable\(` + typeRegex + `\)$`),
+			fieldType:  data.FieldTypeNullableTime,
+			scanType:   reflect.PointerTo(reflect.PointerTo(reflect.TypeOf(time.Time{}))),
+			convert: func(in interface{}) (interface{}, error) {
+				return *in.(**time.Time), nil
+			},
+		}
+	}
+
+	add("DateTime", `DateTime(\('[^']*'\))?`)
+	for precision := 0; precision <= 9; precision++ {
+		add(fmt.Sprintf("DateTime64(%d)", precision), fmt.Sprintf(`DateTime64\(%d(, *'[^']*')?\)`, precision))
+	}
+	return converters
+}
@@ -0,0 +1,160 @@
+package converters_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+	"github.com/hydrolix/plugin/pkg/converters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timeDriver is a database/sql connector returning a single column of the given type and values
+type timeDriver struct {
+	columnType string
+	values     []driver.Value
+}
+
+func (d *timeDriver) Open(string) (driver.Conn, error)    { return d, nil }
+func (d *timeDriver) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (d *timeDriver) Close() error                        { return nil }
+func (d *timeDriver) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+func (d *timeDriver) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &timeRows{timeDriver: d}, nil
+}
+
+type timeRows struct {
+	*timeDriver
+	row int
+}
+
+func (r *timeRows) Columns() []string { return []string{"ts"} }
+func (r *timeRows) Close() error      { return nil }
+func (r *timeRows) Next(dest []driver.Value) error {
+	if r.row == len(r.values) {
+		return io.EOF
+	}
+	dest[0] = r.values[r.row]
+	r.row++
+	return nil
+}
+func (r *timeRows) ColumnTypeDatabaseTypeName(int) string { return r.columnType }
+
+// queryFrame reads the values through database/sql and sqlutil like sqlds does
+func queryFrame(t *testing.T, columnType string, values ...driver.Value) *data.Frame {
+	db := sql.OpenDB(&timeDriver{columnType: columnType, values: values})
+	t.Cleanup(func() { _ = db.Close() })
+	rows, err := db.Query("select ts")
+	require.NoError(t, err)
+	frame, err := sqlutil.FrameFromRows(rows, -1, converters.Converters...)
+	require.NoError(t, err)
+	return frame
+}
+
+func (d *timeDriver) Connect(context.Context) (driver.Conn, error) { return d, nil }
+func (d *timeDriver) Driver() driver.Driver                        { return d }
+
+func TestDateTime64RoundTrip(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	ts := time.Date(2025, 2, 11, 1, 1, 1, 123456789, tokyo)
+
+	for _, precision := range []int{0, 3, 6, 9} {
+		want := ts.Truncate(time.Duration(nanosPerDigit(precision)))
+		for _, columnType := range []string{
+			dateTime64(precision, "'Asia/Tokyo'"),
+			"Nullable(" + dateTime64(precision, "'Asia/Tokyo'") + ")",
+		} {
+			t.Run(columnType, func(t *testing.T) {
+				values := []driver.Value{want}
+				if strings.HasPrefix(columnType, "Nullable(") {
+					values = append(values, nil)
+				}
+				frame := queryFrame(t, columnType, values...)
+				field := frame.Fields[0]
+
+				v, ok := field.ConcreteAt(0)
+				require.True(t, ok)
+				actual := v.(time.Time)
+				assert.True(t, want.Equal(actual), "%s != %s", want, actual)
+				assert.Equal(t, want.Nanosecond(), actual.Nanosecond())
+				if precision == 9 {
+					assert.Equal(t, 123456789, actual.Nanosecond())
+				}
+				assert.Equal(t, "Asia/Tokyo", actual.Location().String())
+
+				if len(values) > 1 {
+					assert.Equal(t, data.FieldTypeNullableTime, field.Type())
+					assert.Nil(t, field.At(1))
+				}
+			})
+		}
+	}
+}
+
+func TestDateTimeTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	// clickhouse-go scans values in the timezone of the column
+	ts := time.Date(2025, 2, 11, 1, 1, 1, 0, berlin)
+
+	frame := queryFrame(t, "DateTime('Europe/Berlin')", ts)
+	actual := frame.Fields[0].At(0).(time.Time)
+	assert.True(t, ts.Equal(actual))
+	assert.Equal(t, "Europe/Berlin", actual.Location().String())
+
+	frame = queryFrame(t, "DateTime64(3)", ts.In(time.UTC))
+	actual = frame.Fields[0].At(0).(time.Time)
+	assert.Equal(t, "UTC", actual.Location().String())
+}
+
+func TestParseTimeColumn(t *testing.T) {
+	for columnType, want := range map[string]converters.TimeColumn{
+		"DateTime":                                  {},
+		"DateTime('Europe/Berlin')":                 {Timezone: "Europe/Berlin"},
+		"DateTime64(3)":                             {Precision: 3},
+		"DateTime64(9, 'Asia/Tokyo')":               {Precision: 9, Timezone: "Asia/Tokyo"},
+		"Nullable(DateTime64(6,'UTC'))":             {Precision: 6, Timezone: "UTC"},
+		"LowCardinality(Nullable(DateTime('UTC')))": {Timezone: "UTC"},
+	} {
+		column, ok := converters.ParseTimeColumn(columnType)
+		assert.True(t, ok, columnType)
+		assert.Equal(t, want, column, columnType)
+	}
+
+	for _, columnType := range []string{"", "Date", "Date32", "String", "Array(DateTime)"} {
+		_, ok := converters.ParseTimeColumn(columnType)
+		assert.False(t, ok, columnType)
+	}
+}
+
+func TestDateIsNotDateTime(t *testing.T) {
+	for _, columnType := range []string{"Date", "Date32"} {
+		assert.Equal(t, "Date", getConverter(columnType).Name)
+	}
+	assert.Equal(t, "Nullable(Date)", getConverter("Nullable(Date32)").Name)
+	assert.Equal(t, "DateTime", getConverter("DateTime").Name)
+	assert.Equal(t, "DateTime", getConverter("DateTime('UTC')").Name)
+	assert.Equal(t, "DateTime64(3)", getConverter("DateTime64(3, 'UTC')").Name)
+	assert.Equal(t, "Nullable(DateTime64(9))", getConverter("Nullable(DateTime64(9))").Name)
+}
+
+func dateTime64(precision int, timezone string) string {
+	return fmt.Sprintf("DateTime64(%d, %s)", precision, timezone)
+}
+
+func nanosPerDigit(precision int) int64 {
+	n := int64(1)
+	for i := precision; i < 9; i++ {
+		n *= 10
+	}
+	return n
+}
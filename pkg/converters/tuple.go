@@ -0,0 +1,60 @@
+package converters
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+)
+
+// Elements of named tuples start with their name followed by a space, names may be quoted with backticks. Elements
+// of unnamed tuples start with their type, directly followed by its parameters or the next element.
+const (
+	namedTupleRegex   = `Tuple\((\w+|` + "`[^`]+`" + `)\s`
+	unnamedTupleRegex = `Tuple\(\w+[(,)]`
+)
+
+// tupleConverters converts Tuple columns to JSON objects, or JSON arrays for unnamed tuples, and Nested columns, which
+// clickhouse-go reports as arrays of named tuples, to JSON arrays of objects. Unlike Array() and Map() converters, their
+// field type is FieldTypeJSON, so tuple fields can be told apart and expanded to a field per element.
+//
+// They must precede the other converters, whose Array() regex matches arrays of tuples as well.
+func tupleConverters() []sqlutil.Converter {
+	converters := map[string]Converter{
+		"Tuple()": {
+			matchRegex: regexp.MustCompile(`^` + namedTupleRegex),
+			scanType:   reflect.TypeOf((*interface{})(nil)).Elem(),
+		},
+		"Tuple(unnamed)": {
+			matchRegex: regexp.MustCompile(`^` + unnamedTupleRegex),
+			scanType:   reflect.TypeOf([]interface{}{}),
+		},
+		"Nested()": {
+			matchRegex: regexp.MustCompile(`^Array\(` + namedTupleRegex),
+			scanType:   reflect.TypeOf([]map[string]interface{}{}),
+		},
+		"Array(Tuple(unnamed))": {
+			matchRegex: regexp.MustCompile(`^Array\(` + unnamedTupleRegex),
+			scanType:   reflect.TypeOf([][]interface{}{}),
+		},
+	}
+
+	list := make([]sqlutil.Converter, 0, len(converters))
+	for name, converter := range converters {
+		converter.fieldType = data.FieldTypeJSON
+		converter.convert = tupleConverter
+		list = append(list, converter.toSqlConverter(name))
+	}
+	return list
+}
+
+// tupleConverter transforms the scanned tuple value to json
+func tupleConverter(in interface{}) (interface{}, error) {
+	b, err := json.Marshal(reflect.ValueOf(in).Elem().Interface())
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(b), nil
+}
@@ -0,0 +1,221 @@
+// Package macros provides Hydrolix plugin's backend implementation of the query editor macros.
+package macros
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+)
+
+// allValues is the value Grafana assigns to a template variable when "All" is selected
+const allValues = "$__all"
+
+// templateVarRegex matches a template variable reference: $var, ${var} or ${var:format}
+var templateVarRegex = regexp.MustCompile(`^\$(\w+|\{\w+(:\w+)?})$`)
+
+// ErrArguments matches the errors of macros called with missing or invalid arguments. Errors of the argument
+// count wrap sqlutil.ErrorBadArgumentCount instead.
+var ErrArguments = errors.New("invalid macro arguments")
+
+// argumentError is an error of the arguments of a macro matching ErrArguments
+type argumentError string
+
+func (e argumentError) Error() string        { return string(e) }
+func (e argumentError) Is(target error) bool { return target == ErrArguments }
+
+// Macros contains all macros supported by the query editor, see src/editor/macros.ts
+var Macros = sqlutil.Macros{
+	"fromTime":        fromTime,
+	"toTime":          toTime,
+	"fromTime_ms":     fromTimeMs,
+	"toTime_ms":       toTimeMs,
+	"timeFilter":      timeFilter,
+	"timeFilter_ms":   timeFilterMs,
+	"dateFilter":      dateFilter,
+	"dateTimeFilter":  dateTimeFilter,
+	"dt":              dateTimeFilter,
+	"timeInterval":    timeInterval,
+	"timeInterval_ms": timeIntervalMs,
+	"interval_s":      intervalSeconds,
+	"conditionalAll":  conditionalAll,
+	"adHocFilter":     adHocFilter,
+}
+
+// fromTime returns the time range start as Unix seconds datetime
+//
+//	$__fromTime => toDateTime(1415792726)
+func fromTime(query *sqlutil.Query, _ []string) (string, error) {
+	return toDateTime(query.TimeRange.From), nil
+}
+
+// toTime returns the time range end as Unix seconds datetime
+//
+//	$__toTime => toDateTime(1447328726)
+func toTime(query *sqlutil.Query, _ []string) (string, error) {
+	return toDateTime(query.TimeRange.To), nil
+}
+
+// fromTimeMs returns the time range start as Unix milliseconds datetime
+//
+//	$__fromTime_ms => fromUnixTimestamp64Milli(1415792726123)
+func fromTimeMs(query *sqlutil.Query, _ []string) (string, error) {
+	return toDateTime64(query.TimeRange.From), nil
+}
+
+// toTimeMs returns the time range end as Unix milliseconds datetime
+//
+//	$__toTime_ms => fromUnixTimestamp64Milli(1447328726456)
+func toTimeMs(query *sqlutil.Query, _ []string) (string, error) {
+	return toDateTime64(query.TimeRange.To), nil
+}
+
+// timeFilter returns a condition filtering the column by the time range in seconds
+//
+//	$__timeFilter(time) => time >= toDateTime(1415792726) AND time <= toDateTime(1447328726)
+func timeFilter(query *sqlutil.Query, args []string) (string, error) {
+	column, err := columnArg("timeFilter", query, args)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s >= %s AND %s <= %s",
+		column, toDateTime(query.TimeRange.From), column, toDateTime(query.TimeRange.To)), nil
+}
+
+// timeFilterMs returns a condition filtering the column by the time range in milliseconds
+//
+//	$__timeFilter_ms(time) => time >= fromUnixTimestamp64Milli(1415792726123) AND time <= fromUnixTimestamp64Milli(1447328726456)
+func timeFilterMs(query *sqlutil.Query, args []string) (string, error) {
+	column, err := columnArg("timeFilter_ms", query, args)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s >= %s AND %s <= %s",
+		column, toDateTime64(query.TimeRange.From), column, toDateTime64(query.TimeRange.To)), nil
+}
+
+// dateFilter returns a condition filtering the column by the time range dates
+//
+//	$__dateFilter(date) => date >= toDate('2022-10-21') AND date <= toDate('2022-10-23')
+func dateFilter(query *sqlutil.Query, args []string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("%w: macro $__dateFilter expects 1 argument", sqlutil.ErrorBadArgumentCount)
+	}
+	return fmt.Sprintf("%s >= %s AND %s <= %s",
+		args[0], toDate(query.TimeRange.From), args[0], toDate(query.TimeRange.To)), nil
+}
+
+// dateTimeFilter combines dateFilter for the first column and timeFilter for the second one
+//
+//	$__dateTimeFilter(date, time) => $__dateFilter(date) AND $__timeFilter(time)
+func dateTimeFilter(query *sqlutil.Query, args []string) (string, error) {
+	if len(args) != 2 || args[0] == "" || args[1] == "" {
+		return "", fmt.Errorf("%w: macro $__dateTimeFilter expects 2 arguments", sqlutil.ErrorBadArgumentCount)
+	}
+	dateCondition, err := dateFilter(query, args[:1])
+	if err != nil {
+		return "", err
+	}
+	timeCondition, err := timeFilter(query, args[1:])
+	if err != nil {
+		return "", err
+	}
+	return dateCondition + " AND " + timeCondition, nil
+}
+
+// timeInterval rounds the column to the query interval start in seconds
+//
+//	$__timeInterval(time) => toStartOfInterval(toDateTime(time), INTERVAL 20 second)
+func timeInterval(query *sqlutil.Query, args []string) (string, error) {
+	column, err := columnArg("timeInterval", query, args)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("toStartOfInterval(toDateTime(%s), INTERVAL %d second)", column, intervalSecondsOf(query)), nil
+}
+
+// timeIntervalMs rounds the column to the query interval start in milliseconds
+//
+//	$__timeInterval_ms(time) => toStartOfInterval(toDateTime64(time, 3), INTERVAL 20 millisecond)
+func timeIntervalMs(query *sqlutil.Query, args []string) (string, error) {
+	column, err := columnArg("timeInterval_ms", query, args)
+	if err != nil {
+		return "", err
+	}
+	ms := query.Interval.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return fmt.Sprintf("toStartOfInterval(toDateTime64(%s, 3), INTERVAL %d millisecond)", column, ms), nil
+}
+
+// intervalSeconds returns the query interval in seconds
+//
+//	$__interval_s => 20
+func intervalSeconds(query *sqlutil.Query, _ []string) (string, error) {
+	return fmt.Sprintf("%d", intervalSecondsOf(query)), nil
+}
+
+// conditionalAll returns the condition unless the template variable selects every value, 1=1 otherwise.
+// On the backend template variables are already interpolated, so an empty value, the "$__all" value or
+// a variable reference left as is (e.g. alerting queries have no dashboard variables) mean all values.
+//
+//	$__conditionalAll(host in ('a', 'b'), 'a', 'b') => host in ('a', 'b')
+//	$__conditionalAll(host in ($host), $host) => 1=1
+func conditionalAll(_ *sqlutil.Query, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", argumentError("Macro $__conditionalAll should contain 2 parameters")
+	}
+	// an interpolated multi-value variable is split by commas into several arguments
+	value := strings.TrimSpace(strings.Join(args[1:], ","))
+	unquoted := strings.Trim(value, "'\"")
+	if unquoted == "" || unquoted == allValues || templateVarRegex.MatchString(value) {
+		return "1=1", nil
+	}
+	return args[0], nil
+}
+
+// adHocFilter returns conditions of ad hoc filters. Queries executed without a dashboard (alerting,
+// reporting, recorded queries) have no ad hoc filters, so the macro always matches everything there.
+//
+//	$__adHocFilter => 1=1
+func adHocFilter(_ *sqlutil.Query, _ []string) (string, error) {
+	return "1=1", nil
+}
+
+// columnArg returns the single column argument of a macro falling back to the query column
+func columnArg(name string, query *sqlutil.Query, args []string) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("%w: macro $__%s expects 1 argument, received %d", sqlutil.ErrorBadArgumentCount, name, len(args))
+	}
+	if len(args) == 1 && args[0] != "" {
+		return args[0], nil
+	}
+	if query.Column != "" {
+		return query.Column, nil
+	}
+	return "", argumentError(fmt.Sprintf("macro $__%s requires a column name", name))
+}
+
+func intervalSecondsOf(query *sqlutil.Query) int64 {
+	s := int64(query.Interval.Seconds())
+	if s < 1 {
+		return 1
+	}
+	return s
+}
+
+func toDateTime(t time.Time) string {
+	return fmt.Sprintf("toDateTime(%d)", t.Unix())
+}
+
+func toDateTime64(t time.Time) string {
+	return fmt.Sprintf("fromUnixTimestamp64Milli(%d)", t.UnixMilli())
+}
+
+func toDate(t time.Time) string {
+	return fmt.Sprintf("toDate('%s')", t.UTC().Format(time.DateOnly))
+}
@@ -0,0 +1,102 @@
+package macros
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixtures are the macro cases shared with the frontend tests of src/editor/macros.test.ts
+type fixtures struct {
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	IntervalMs int64     `json:"intervalMs"`
+	Cases      []struct {
+		Name     string `json:"name"`
+		Macro    string `json:"macro"`
+		Query    string `json:"query"`
+		Expected string `json:"expected"`
+	} `json:"cases"`
+}
+
+func readFixtures(t *testing.T) fixtures {
+	b, err := os.ReadFile("testdata/macros.json")
+	require.NoError(t, err)
+	var f fixtures
+	require.NoError(t, json.Unmarshal(b, &f))
+	return f
+}
+
+func testQuery(t *testing.T, sql string) *sqlutil.Query {
+	f := readFixtures(t)
+	return &sqlutil.Query{
+		RawSQL:    sql,
+		Interval:  time.Duration(f.IntervalMs) * time.Millisecond,
+		TimeRange: backend.TimeRange{From: f.From, To: f.To},
+	}
+}
+
+// interpolate applies the macros with the SDK interpolator, which sqlds uses for the queries of the datasource.
+// Escaped macros ($$__name) are handled by sqlds before.
+func interpolate(query *sqlutil.Query) (string, error) {
+	return sqlutil.Interpolate(query, Macros)
+}
+
+func TestMacros(t *testing.T) {
+	covered := map[string]bool{}
+	for _, tt := range readFixtures(t).Cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			actual, err := interpolate(testQuery(t, tt.Query))
+			require.NoError(t, err)
+			assert.Equal(t, tt.Expected, actual)
+		})
+		covered[tt.Macro] = true
+	}
+
+	for name := range Macros {
+		assert.True(t, covered[name], "macro %s has no fixture", name)
+	}
+}
+
+func TestMacrosColumnFallback(t *testing.T) {
+	query := testQuery(t, "select 1 from t where $__timeFilter()")
+	query.Column = "primary"
+	actual, err := interpolate(query)
+	require.NoError(t, err)
+	assert.Equal(t, "select 1 from t where primary >= toDateTime(1415792726) AND primary <= toDateTime(1447328726)", actual)
+
+	_, err = interpolate(testQuery(t, "select 1 from t where $__timeFilter()"))
+	assert.EqualError(t, err, "macro $__timeFilter requires a column name")
+	assert.ErrorIs(t, err, ErrArguments)
+}
+
+func TestMacrosMinimalInterval(t *testing.T) {
+	query := testQuery(t, "select $__interval_s, $__timeInterval_ms(time)")
+	query.Interval = 0
+	actual, err := interpolate(query)
+	require.NoError(t, err)
+	assert.Equal(t, "select 1, toStartOfInterval(toDateTime64(time, 3), INTERVAL 1 millisecond)", actual)
+}
+
+func TestMacrosBadArguments(t *testing.T) {
+	for _, query := range []string{
+		"select 1 from t where $__dateFilter()",
+		"select 1 from t where $__dateTimeFilter(date)",
+		"select 1 from t where $__timeFilter(a, b)",
+	} {
+		_, err := interpolate(testQuery(t, query))
+		assert.ErrorIs(t, err, sqlutil.ErrorBadArgumentCount, query)
+	}
+}
+
+func TestConditionalAllParameters(t *testing.T) {
+	_, err := interpolate(testQuery(t, "select foo from table where $__conditionalAll(bar in ($bar));"))
+	assert.EqualError(t, err, "Macro $__conditionalAll should contain 2 parameters")
+	assert.ErrorIs(t, err, ErrArguments)
+}
@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// columnTypesCollector receives the ClickHouse types of the columns of a
+// query's result. MutateQuery stores it in the query context, the
+// connection running the query records the types of its rows and
+// MutateResponse converts fields by the type of their column.
+type columnTypesCollector struct {
+	mu    sync.Mutex
+	types map[string]string
+}
+
+type columnTypesCtxKey struct{}
+
+func withColumnTypesCollector(ctx context.Context) context.Context {
+	return context.WithValue(ctx, columnTypesCtxKey{}, &columnTypesCollector{})
+}
+
+func columnTypesCollectorFromContext(ctx context.Context) *columnTypesCollector {
+	c, _ := ctx.Value(columnTypesCtxKey{}).(*columnTypesCollector)
+	return c
+}
+
+// collect records the column types of rows, replacing those of a previous
+// attempt of the query.
+func (c *columnTypesCollector) collect(rows driver.Rows) {
+	typed, ok := rows.(driver.RowsColumnTypeDatabaseTypeName)
+	if !ok {
+		return
+	}
+	columns := rows.Columns()
+	types := make(map[string]string, len(columns))
+	for i, name := range columns {
+		types[name] = typed.ColumnTypeDatabaseTypeName(i)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.types = types
+}
+
+// columnType returns the ClickHouse type of the column a field is named
+// after, "" when it isn't known.
+func (c *columnTypesCollector) columnType(name string) string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.types[name]
+}
+
+// openDB opens a connection pool like clickhouse.OpenDB whose queries
+// report their column types to the columnTypesCollector of their context.
+// Pool limits are set by applyConnectionPool.
+func openDB(opts *clickhouse.Options) *sql.DB {
+	return sql.OpenDB(columnTypesConnector{clickhouse.Connector(opts)})
+}
+
+type columnTypesConnector struct {
+	driver.Connector
+}
+
+func (c columnTypesConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if std, ok := conn.(stdConn); ok {
+		return columnTypesConn{std}, nil
+	}
+	return conn, nil
+}
+
+// stdConn is the set of interfaces implemented by clickhouse-go
+// connections, columnTypesConn keeps all of them so database/sql uses the
+// connection as it would without wrapper.
+type stdConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.NamedValueChecker
+}
+
+type columnTypesConn struct {
+	stdConn
+}
+
+func (c columnTypesConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.stdConn.QueryContext(ctx, query, args)
+	if err != nil {
+		return rows, err
+	}
+	if collector := columnTypesCollectorFromContext(ctx); collector != nil {
+		collector.collect(rows)
+	}
+	return rows, nil
+}
@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// typedConn is a connection implementing the interfaces of clickhouse-go connections, its queries return no rows of
+// the columns a String and b Tuple(c Int64)
+type typedConn struct{}
+
+func (typedConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (typedConn) Close() error                        { return nil }
+func (typedConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+func (typedConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+func (typedConn) PrepareContext(context.Context, string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+func (typedConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+func (typedConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return typedRows{}, nil
+}
+func (typedConn) Ping(context.Context) error                   { return nil }
+func (typedConn) ResetSession(context.Context) error           { return nil }
+func (typedConn) CheckNamedValue(*driver.NamedValue) error     { return nil }
+func (typedConn) Connect(context.Context) (driver.Conn, error) { return typedConn{}, nil }
+func (typedConn) Driver() driver.Driver                        { return nil }
+
+type typedRows struct{}
+
+func (typedRows) Columns() []string         { return []string{"a", "b"} }
+func (typedRows) Close() error              { return nil }
+func (typedRows) Next([]driver.Value) error { return io.EOF }
+func (typedRows) ColumnTypeDatabaseTypeName(i int) string {
+	return []string{"String", "Tuple(c Int64)"}[i]
+}
+
+func TestColumnTypesCollector(t *testing.T) {
+	db := sql.OpenDB(columnTypesConnector{typedConn{}})
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := withColumnTypesCollector(context.Background())
+	rows, err := db.QueryContext(ctx, "SELECT a, b")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	collector := columnTypesCollectorFromContext(ctx)
+	assert.Equal(t, "String", collector.columnType("a"))
+	assert.Equal(t, "Tuple(c Int64)", collector.columnType("b"))
+	assert.Equal(t, "", collector.columnType("c"))
+
+	// queries without collector run as is, and a missing collector knows no column
+	rows, err = db.QueryContext(context.Background(), "SELECT a, b")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	assert.Equal(t, "", columnTypesCollectorFromContext(context.Background()).columnType("a"))
+}
@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// Compression methods of query results.
+const (
+	compressionNone    = "none"
+	compressionLZ4     = "lz4"
+	compressionZSTD    = "zstd"
+	compressionGZIP    = "gzip"
+	compressionDeflate = "deflate"
+	compressionBrotli  = "br"
+)
+
+// defaultHTTPCompressionLevel is the client side level of HTTP compression
+// when the datasource doesn't configure one, same as clickhouse-go's default.
+const defaultHTTPCompressionLevel = 3
+
+var compressionMethods = map[string]clickhouse.CompressionMethod{
+	compressionNone:    clickhouse.CompressionNone,
+	compressionLZ4:     clickhouse.CompressionLZ4,
+	compressionZSTD:    clickhouse.CompressionZSTD,
+	compressionGZIP:    clickhouse.CompressionGZIP,
+	compressionDeflate: clickhouse.CompressionDeflate,
+	compressionBrotli:  clickhouse.CompressionBrotli,
+}
+
+// compression returns the compression of connections using protocol and the
+// settings that make the query head compress results accordingly.
+func (s hydrolixSettings) compression(protocol clickhouse.Protocol) (*clickhouse.Compression, map[string]any, error) {
+	name := s.Compression
+	if name == "" {
+		name = compressionLZ4
+		if protocol == clickhouse.HTTP {
+			name = compressionNone
+		}
+	}
+	method, ok := compressionMethods[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid compression: %q", s.Compression)
+	}
+
+	level := s.CompressionLevel
+	settings := map[string]any{}
+	switch name {
+	case compressionNone, compressionLZ4:
+		if level != 0 {
+			return nil, nil, fmt.Errorf("invalid compression level: %s doesn't support levels", name)
+		}
+	case compressionZSTD:
+		if level < 0 || level > 22 {
+			return nil, nil, fmt.Errorf("invalid compression level: %d, zstd supports levels 1-22", level)
+		}
+		settings["network_compression_method"] = "ZSTD"
+		if level > 0 {
+			settings["network_zstd_compression_level"] = strconv.Itoa(level)
+		}
+	default:
+		if protocol != clickhouse.HTTP {
+			return nil, nil, fmt.Errorf("invalid compression: %s is supported by the HTTP protocol only", name)
+		}
+		if level < 0 || level > 9 {
+			return nil, nil, fmt.Errorf("invalid compression level: %d, %s supports levels 1-9", level, name)
+		}
+		settings["enable_http_compression"] = "1"
+		if level > 0 {
+			settings["http_zlib_compression_level"] = strconv.Itoa(level)
+		} else {
+			level = defaultHTTPCompressionLevel
+		}
+	}
+
+	return &clickhouse.Compression{Method: method, Level: level}, settings, nil
+}
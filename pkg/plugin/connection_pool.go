@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// connectionPoolStats is a JSON friendly form of sql.DBStats reported by the
+// datasource health check.
+type connectionPoolStats struct {
+	MaxOpenConnections int    `json:"maxOpenConnections"`
+	OpenConnections    int    `json:"openConnections"`
+	InUse              int    `json:"inUse"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"waitCount"`
+	WaitDuration       string `json:"waitDuration"`
+	MaxIdleClosed      int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64  `json:"maxLifetimeClosed"`
+}
+
+func newConnectionPoolStats(s sql.DBStats) connectionPoolStats {
+	return connectionPoolStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration.String(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+// connectionPool keeps the last connection pool opened by Hydrolix.Connect so
+// its statistics can be reported by the health check.
+type connectionPool struct {
+	mu sync.Mutex
+	db *sql.DB
+}
+
+func (p *connectionPool) set(db *sql.DB) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.db = db
+}
+
+// get returns the tracked pool, false if no pool was opened yet.
+func (p *connectionPool) get() (*sql.DB, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.db, p.db != nil
+}
+
+// stats returns statistics of the tracked pool, false if no pool was opened yet.
+func (p *connectionPool) stats() (connectionPoolStats, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.db == nil {
+		return connectionPoolStats{}, false
+	}
+	return newConnectionPoolStats(p.db.Stats()), true
+}
@@ -2,22 +2,111 @@ package plugin
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/hydrolix/plugin/pkg/api"
+	"github.com/hydrolix/plugin/pkg/converters"
 	"github.com/hydrolix/sqlds/v5"
 )
 
+// Datasource extends sqlds datasource with Hydrolix specific health check details
+type Datasource struct {
+	*sqlds.HydrolixDatasource
+	driver *Hydrolix
+}
+
 func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
-	conn, err := sqlds.NewConnector(ctx, NewHydrolix(), settings)
+	hdxSettings, err := parseHydrolixSettings(settings.JSONData)
+	if err != nil {
+		return nil, backend.DownstreamError(err)
+	}
+	driver := NewHydrolix()
+	driver.converters = converters.New(hdxSettings.converterOptions())
+	driver.widenFloat32 = hdxSettings.WidenFloat32
+	driver.expandTuples = hdxSettings.ExpandTuples
+	driver.flattenJSON = hdxSettings.FlattenJSON
+	driver.splitGeoPoints = hdxSettings.GeoPointFormat != geoPointFormatGeoJSON
+	driver.safeIntegers = hdxSettings.SafeIntegers
+	conn, err := sqlds.NewConnector(ctx, driver, settings)
 	if err != nil {
 		return nil, backend.DownstreamError(err)
 	}
 	ds := &sqlds.HydrolixDatasource{
 		Connector: conn,
 	}
-	ds.RegisterRoutes(api.Routes(ds))
-	newDatasource, err := ds.NewDatasource(ctx, settings)
-	return newDatasource, err
+	resourceDB := func(ctx context.Context, header http.Header) (*sql.DB, error) {
+		return driver.resourceDB(ctx, settings, header)
+	}
+	ds.RegisterRoutes(api.Routes(ds, resourceDB, hdxSettings.schemaCacheTTL()))
+	instance, err := ds.NewDatasource(ctx, settings)
+	if err != nil {
+		return instance, err
+	}
+	// the Hydrolix health check details and connection pools require the sqlds datasource
+	hds, ok := instance.(*sqlds.HydrolixDatasource)
+	if !ok {
+		if d, ok := instance.(instancemgmt.InstanceDisposer); ok {
+			d.Dispose()
+		}
+		return nil, fmt.Errorf("unexpected sqlds datasource %T", instance)
+	}
+	return &Datasource{HydrolixDatasource: hds, driver: driver}, nil
+}
+
+// Dispose closes the connections of sqlds and the per-user connection pools opened for resource requests only
+func (d *Datasource) Dispose() {
+	d.HydrolixDatasource.Dispose()
+	d.driver.userPools.close()
+}
+
+// CheckHealth checks the connection and reports connection pool statistics, the number of per-user connection pools
+// and the health of query heads in the result details
+func (d *Datasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+	res, err := d.HydrolixDatasource.CheckHealth(ctx, req)
+	if err != nil || res == nil {
+		return res, err
+	}
+	details := map[string]any{}
+	if res.Status == backend.HealthStatusOk {
+		if stats, ok := d.driver.poolStats(); ok {
+			details["connectionPool"] = stats
+		}
+	}
+	if n := d.driver.userPoolCount(); n > 0 {
+		details["userConnectionPools"] = n
+	}
+	if hosts, ok := d.driver.hostsHealth(ctx); ok {
+		details["hosts"] = hosts
+		if unhealthy := countUnhealthy(hosts); unhealthy > 0 && res.Status == backend.HealthStatusOk {
+			res.Message = fmt.Sprintf("%s. %d of %d query heads are unavailable", strings.TrimSuffix(res.Message, "."),
+				unhealthy, len(hosts))
+		}
+	}
+	if len(details) == 0 {
+		return res, nil
+	}
+	jsonDetails, err := json.Marshal(details)
+	if err != nil {
+		log.DefaultLogger.Warn("failed to serialize health check details", "err", err)
+		return res, nil
+	}
+	res.JSONDetails = jsonDetails
+	return res, nil
+}
+
+func countUnhealthy(hosts []hostStatus) int {
+	n := 0
+	for _, h := range hosts {
+		if !h.Healthy {
+			n++
+		}
+	}
+	return n
 }
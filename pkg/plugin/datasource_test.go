@@ -8,7 +8,6 @@ import (
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/hydrolix/plugin/pkg/plugin"
-	"github.com/hydrolix/sqlds/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -75,7 +74,7 @@ func (s *DatasourceTestSuite) TestDatasourceRunQuery() {
 		assert.NoError(t, err)
 
 		switch ds := db.(type) {
-		case *sqlds.HydrolixDatasource:
+		case *plugin.Datasource:
 			_, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{
 				PluginContext: backend.PluginContext{DataSourceInstanceSettings: &settings},
 				Queries: []backend.DataQuery{
@@ -103,7 +102,7 @@ func (s *DatasourceTestSuite) TestDatasourceRunQuery() {
 		assert.NoError(t, err)
 
 		switch ds := db.(type) {
-		case *sqlds.HydrolixDatasource:
+		case *plugin.Datasource:
 			_, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{
 				PluginContext: backend.PluginContext{DataSourceInstanceSettings: &settings},
 				Queries: []backend.DataQuery{
@@ -118,3 +117,72 @@ func (s *DatasourceTestSuite) TestDatasourceRunQuery() {
 		}
 	})
 }
+
+func (s *DatasourceTestSuite) TestCheckHealthReportsConnectionPool() {
+	t := s.T()
+	settings := backend.DataSourceInstanceSettings{
+		Name: "test-hydrolix-native-datasource",
+		JSONData: []byte(fmt.Sprintf(`{
+			"host": "%s","port": %d,"protocol": "native",
+			"username": "%s", "password": "%s",
+			"secure": false, "maxOpenConns": 10, "maxIdleConns": 2
+		}`, s.ChContainer.Hostname, s.ChContainer.NativePort, s.ChContainer.Username, s.ChContainer.Password)),
+	}
+	instance, err := plugin.NewDatasource(context.Background(), settings)
+	assert.NoError(t, err)
+
+	ds, ok := instance.(*plugin.Datasource)
+	if !ok {
+		t.Fatal("wrong sql datasource")
+	}
+	res, err := ds.CheckHealth(context.Background(), &backend.CheckHealthRequest{
+		PluginContext: backend.PluginContext{DataSourceInstanceSettings: &settings},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, backend.HealthStatusOk, res.Status)
+
+	var details struct {
+		ConnectionPool struct {
+			MaxOpenConnections int `json:"maxOpenConnections"`
+		} `json:"connectionPool"`
+	}
+	assert.NoError(t, json.Unmarshal(res.JSONDetails, &details))
+	assert.Equal(t, 10, details.ConnectionPool.MaxOpenConnections)
+}
+
+func (s *DatasourceTestSuite) TestCheckHealthReportsUnavailableQueryHeads() {
+	t := s.T()
+	settings := backend.DataSourceInstanceSettings{
+		Name: "test-hydrolix-native-datasource",
+		JSONData: []byte(fmt.Sprintf(`{
+			"host": "%s","port": %d,"protocol": "native",
+			"username": "%s", "password": "%s",
+			"secure": false, "dialTimeout": "5", "hosts": ["127.0.0.1:1"]
+		}`, s.ChContainer.Hostname, s.ChContainer.NativePort, s.ChContainer.Username, s.ChContainer.Password)),
+	}
+	instance, err := plugin.NewDatasource(context.Background(), settings)
+	assert.NoError(t, err)
+
+	ds, ok := instance.(*plugin.Datasource)
+	if !ok {
+		t.Fatal("wrong sql datasource")
+	}
+	res, err := ds.CheckHealth(context.Background(), &backend.CheckHealthRequest{
+		PluginContext: backend.PluginContext{DataSourceInstanceSettings: &settings},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, backend.HealthStatusOk, res.Status)
+	assert.Contains(t, res.Message, "1 of 2 query heads are unavailable")
+
+	var details struct {
+		Hosts []struct {
+			Address string `json:"address"`
+			Healthy bool   `json:"healthy"`
+		} `json:"hosts"`
+	}
+	assert.NoError(t, json.Unmarshal(res.JSONDetails, &details))
+	assert.Len(t, details.Hosts, 2)
+	assert.True(t, details.Hosts[0].Healthy)
+	assert.Equal(t, "127.0.0.1:1", details.Hosts[1].Address)
+	assert.False(t, details.Hosts[1].Healthy)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
 	hdxbuild "github.com/hydrolix/plugin/pkg/build"
 	"github.com/hydrolix/plugin/pkg/converters"
+	"github.com/hydrolix/plugin/pkg/macros"
 	"github.com/hydrolix/sqlds/v5"
 	"github.com/hydrolix/sqlds/v5/models"
 	"github.com/pkg/errors"
@@ -28,6 +29,15 @@ import (
 // Hydrolix defines how to connect to a Hydrolix datasource
 type Hydrolix struct {
 	querySettingsContextHandler func(context.Context, map[string]any) context.Context
+	pool                        connectionPool
+	heads                       queryHeads
+	userPools                   userPools
+	converters                  []sqlutil.Converter
+	widenFloat32                bool
+	expandTuples                bool
+	flattenJSON                 bool
+	splitGeoPoints              bool
+	safeIntegers                bool
 }
 
 var (
@@ -42,7 +52,11 @@ var (
 
 // NewHydrolix creates plugin instance with default parameters
 func NewHydrolix() *Hydrolix {
-	return &Hydrolix{querySettingsContextHandler: clickhouseContextHandler}
+	return &Hydrolix{
+		querySettingsContextHandler: clickhouseContextHandler,
+		converters:                  converters.New(converters.Options{}),
+		splitGeoPoints:              true,
+	}
 }
 
 // getClientInfoProducts reads build information of grafana and plugin
@@ -67,10 +81,37 @@ func getClientInfoProducts(ctx context.Context) (products []struct{ Name, Versio
 
 // Connect opens a sql.DB connection using datasource settings
 func (h *Hydrolix) Connect(ctx context.Context, config backend.DataSourceInstanceSettings, args json.RawMessage) (*sql.DB, error) {
+	return h.connect(ctx, config, args)
+}
+
+// connect opens a sql.DB connection, or returns the pool of the forwarded identity of args
+func (h *Hydrolix) connect(ctx context.Context, config backend.DataSourceInstanceSettings, args json.RawMessage) (*sql.DB, error) {
 	settings, err := models.NewPluginSettings(ctx, config)
 	if err != nil {
 		return nil, err
 	}
+	hdxSettings, err := parseHydrolixSettings(config.JSONData)
+	if err != nil {
+		return nil, err
+	}
+
+	// forwardOAuth pools are kept per identity, a pool opened with another
+	// user's token is never returned
+	var userPoolID string
+	var oAuthToken string
+	if settings.CredentialsType == "forwardOAuth" {
+		token, ok := getOAuthToken(args)
+		if !ok {
+			return nil, fmt.Errorf("cannot get auth header")
+		}
+		orgId, _ := getOrgId(args)
+		oAuthToken = token
+		userPoolID = userPoolKey(orgId, token)
+		if db, ok := h.userPools.get(userPoolID); ok {
+			log.DefaultLogger.Debug("reuse user connection pool", "name", config.Name)
+			return db, nil
+		}
+	}
 
 	dt, _ := strconv.Atoi(settings.DialTimeout)
 	qt, _ := strconv.Atoi(settings.QueryTimeout)
@@ -80,30 +121,35 @@ func (h *Hydrolix) Connect(ctx context.Context, config backend.DataSourceInstanc
 		protocol = clickhouse.HTTP
 	}
 
-	compression := clickhouse.CompressionLZ4
-	if protocol == clickhouse.HTTP {
-		compression = clickhouse.CompressionNone
+	compression, compressionSettings, err := hdxSettings.compression(protocol)
+	if err != nil {
+		return nil, err
 	}
 
 	var tlsConfig *tls.Config
 	if settings.Secure {
-		tlsConfig = &tls.Config{
-			InsecureSkipVerify: settings.SkipTlsVerify,
+		tlsConfig, err = newTLSConfig(settings.SkipTlsVerify, hdxSettings.TLSServerName, config.DecryptedSecureJSONData)
+		if err != nil {
+			return nil, err
 		}
 	}
 
+	addrs, err := hostAddresses(settings.Host, settings.Port, hdxSettings.Hosts)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(dt)*time.Second)
 	defer cancel()
 
 	opts := &clickhouse.Options{
-		Addr: []string{fmt.Sprintf("%s:%d", settings.Host, settings.Port)},
+		Addr:             addrs,
+		ConnOpenStrategy: hdxSettings.connOpenStrategy(),
 
 		ClientInfo: clickhouse.ClientInfo{
 			Products: getClientInfoProducts(ctx),
 		},
-		Compression: &clickhouse.Compression{
-			Method: compression,
-		},
+		Compression: compression,
 		Protocol:    protocol,
 		HttpUrlPath: settings.Path,
 		DialTimeout: time.Duration(dt) * time.Second,
@@ -113,9 +159,20 @@ func (h *Hydrolix) Connect(ctx context.Context, config backend.DataSourceInstanc
 		BlockBufferSize: 2,
 	}
 
+	outbound, err := newOutboundProxy(hdxSettings, config.DecryptedSecureJSONData[proxyPasswordKey], opts.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if outbound != nil && protocol == clickhouse.Native {
+		opts.DialContext = outbound.dialContext(tlsConfig)
+	}
+
 	opts.TransportFunc = func(t *http.Transport) (http.RoundTripper, error) {
 		t.DisableCompression = false
-		return t, nil
+		if outbound != nil {
+			t.Proxy = outbound.transportProxy
+		}
+		return &statsStrippingTransport{next: t}, nil
 	}
 
 	if settings.CredentialsType == "userAccount" || settings.CredentialsType == "" {
@@ -132,23 +189,12 @@ func (h *Hydrolix) Connect(ctx context.Context, config backend.DataSourceInstanc
 					"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", settings.UserName, settings.Password))),
 				}
 			}
-			// native format
-			opts.Settings = map[string]any{
-				"hdx_query_output_format": "Native",
-				//"hdx_query_streaming_result": "true",
-			}
+			opts.Settings = httpQuerySettings(hdxSettings)
 		}
 	} else {
-		token := ""
+		token := settings.Token
 		if settings.CredentialsType == "forwardOAuth" {
-			oAuthToken, ok := getOAuthToken(args)
-			if ok {
-				token = oAuthToken
-			} else {
-				return nil, fmt.Errorf("cannot get auth header")
-			}
-		} else {
-			token = settings.Token
+			token = oAuthToken
 		}
 
 		if protocol == clickhouse.HTTP {
@@ -168,11 +214,7 @@ func (h *Hydrolix) Connect(ctx context.Context, config backend.DataSourceInstanc
 			if len(httpHeaders) > 0 {
 				opts.HttpHeaders = httpHeaders
 			}
-			// native format
-			opts.Settings = map[string]any{
-				"hdx_query_output_format": "Native",
-				//"hdx_query_streaming_result": "true",
-			}
+			opts.Settings = httpQuerySettings(hdxSettings)
 		} else {
 			opts.Auth = clickhouse.Auth{
 				Database: settings.DefaultDatabase,
@@ -182,13 +224,37 @@ func (h *Hydrolix) Connect(ctx context.Context, config backend.DataSourceInstanc
 		}
 	}
 
-	db := clickhouse.OpenDB(opts)
+	customHeaders, err := customHTTPHeaders(hdxSettings.HTTPHeaders, config.DecryptedSecureJSONData)
+	if err != nil {
+		return nil, err
+	}
+	if protocol == clickhouse.HTTP {
+		for k, v := range customHeaders {
+			if opts.HttpHeaders == nil {
+				opts.HttpHeaders = map[string]string{}
+			}
+			opts.HttpHeaders[k] = v
+		}
+	}
+
+	for k, v := range compressionSettings {
+		if opts.Settings == nil {
+			opts.Settings = clickhouse.Settings{}
+		}
+		opts.Settings[k] = v
+	}
+
+	// forwardOAuth connections are opened per user, their query heads are
+	// checked by the queries themselves
+	if len(addrs) > 1 && settings.CredentialsType != "forwardOAuth" {
+		statuses := probeHosts(ctx, opts)
+		opts.Addr = healthyFirst(opts.ConnOpenStrategy, statuses)
+		h.heads.set(opts)
+	}
+
+	db := openDB(opts)
 
-	// TODO: add config UI for connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxIdleTime(time.Duration(2) * time.Minute)
-	db.SetConnMaxLifetime(time.Duration(2) * time.Minute)
+	hdxSettings.applyConnectionPool(db)
 
 	select {
 	case <-ctx.Done():
@@ -216,18 +282,61 @@ func (h *Hydrolix) Connect(ctx context.Context, config backend.DataSourceInstanc
 			}
 		}
 	}
+	if userPoolID != "" {
+		expiresAt := tokenExpiry(oAuthToken, time.Now(), hdxSettings.userPoolTTL())
+		db = h.userPools.add(userPoolID, db, expiresAt, hdxSettings.maxUserPools())
+	} else {
+		// the shared pool of the datasource, per-user pools aren't reported
+		h.pool.set(db)
+	}
 	log.DefaultLogger.Debug("connect datasource", "name", config.Name)
 	return db, nil
 }
 
-// Converters defines list of data type converters
+// resourceDB returns the connection pool of resource requests: the pool opened for queries or, for forwarded OAuth
+// identities, the pool of the identity of the request headers.
+func (h *Hydrolix) resourceDB(ctx context.Context, config backend.DataSourceInstanceSettings, header http.Header) (*sql.DB, error) {
+	settings, err := models.NewPluginSettings(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if settings.CredentialsType != "forwardOAuth" {
+		if db, ok := h.pool.get(); ok {
+			return db, nil
+		}
+	}
+	args, err := json.Marshal(map[string]http.Header{sqlds.HeaderKey: header})
+	if err != nil {
+		return nil, err
+	}
+	return h.connect(ctx, config, args)
+}
+
+// poolStats returns statistics of the connection pool opened by Connect
+func (h *Hydrolix) poolStats() (connectionPoolStats, bool) {
+	return h.pool.stats()
+}
+
+// userPoolCount returns the number of connection pools kept for forwarded OAuth identities
+func (h *Hydrolix) userPoolCount() int {
+	return h.userPools.len()
+}
+
+// hostsHealth probes the query heads of a datasource configured with several hosts
+func (h *Hydrolix) hostsHealth(ctx context.Context) ([]hostStatus, bool) {
+	return h.heads.probe(ctx)
+}
+
+// Converters returns the data type converters of this instance, built from the datasource settings when created by
+// NewDatasource
 func (h *Hydrolix) Converters() []sqlutil.Converter {
-	return converters.Converters
+	return h.converters
 }
 
-// Macros returns list of macro functions convert the macros of raw query
+// Macros returns list of macro functions convert the macros of raw query. The backend implementation
+// is used for queries executed without the query editor, e.g. alerting, public dashboards and reporting.
 func (h *Hydrolix) Macros() sqlutil.Macros {
-	return sqlutil.Macros{}
+	return macros.Macros
 }
 
 // Settings reads Json Datasource Plugin's configuration
@@ -239,13 +348,19 @@ func (h *Hydrolix) Settings(ctx context.Context, config backend.DataSourceInstan
 
 	timeoutSec, _ := strconv.Atoi(settings.QueryTimeout)
 
-	return sqlds.DriverSettings{
+	driverSettings := sqlds.DriverSettings{
 		Timeout: time.Second * time.Duration(timeoutSec),
 		FillMode: &data.FillMissing{
 			Mode: data.FillModeNull,
 		},
 		ForwardHeaders: settings.CredentialsType == "forwardOAuth",
 	}
+	if driverSettings.ForwardHeaders {
+		// sqlds reconnects queries running over a per-user pool released by userPools
+		driverSettings.RetryOn = []string{closedPoolError}
+		driverSettings.Retries = 1
+	}
+	return driverSettings
 }
 
 // adminCommentSetting is the ClickHouse setting used to carry Grafana
@@ -545,7 +660,7 @@ func (h *Hydrolix) MutateQueryData(ctx context.Context, req *backend.QueryDataRe
 }
 
 // MutateQuery adds user location timezone metadata if it is available. Also, it rounds the Query Time Range to
-// specified time interval.
+// specified time interval and prepares the collector of X-HDX-Query-Stats reported by the query head.
 func (h *Hydrolix) MutateQuery(ctx context.Context, req backend.DataQuery) (context.Context, backend.DataQuery) {
 	var dataQuery struct {
 		Meta struct {
@@ -554,12 +669,19 @@ func (h *Hydrolix) MutateQuery(ctx context.Context, req backend.DataQuery) (cont
 		Format        int                   `json:"format"`
 		Round         string                `json:"round"`
 		QuerySettings []models.QuerySetting `json:"querySettings"`
+		SafeIntegers  *bool                 `json:"safeIntegers"`
 	}
 
 	if err := json.Unmarshal(req.JSON, &dataQuery); err != nil {
 		return ctx, req
 	}
 
+	ctx = withQueryStatsCollector(ctx)
+	ctx = withColumnTypesCollector(ctx)
+	if dataQuery.SafeIntegers != nil {
+		ctx = withSafeIntegers(ctx, *dataQuery.SafeIntegers)
+	}
+
 	if dataQuery.Meta.TimeZone != "" {
 		loc, err := time.LoadLocation(dataQuery.Meta.TimeZone)
 		if err != nil || loc == nil {
@@ -651,10 +773,39 @@ func clickhouseContextHandler(ctx context.Context, settings map[string]any) cont
 	return clickhouse.Context(ctx, clickhouse.WithSettings(settings))
 }
 
-// MutateResponse converts fields of type FieldTypeNullableJSON to string, except for specific visualizations - traces,
-// tables, and logs.
-func (h *Hydrolix) MutateResponse(_ context.Context, res data.Frames) (data.Frames, error) {
+// MutateResponse attaches query stats collected from the response to the frames and exposes precision and timezone of
+// DateTime columns on their fields. Unless GeoJSON points are configured, it splits Point fields into latitude and
+// longitude fields. When configured, it widens Float32 fields to float64, expands Tuple and Nested fields, flattens
+// JSON objects and returns 64-bit integers beyond the safe range of the frontend as strings. Time, Point, tuple and
+// JSON fields are converted by the column types collected from the query's rows. It converts JSON fields to string, except for specific visualizations - traces, tables, and
+// logs.
+func (h *Hydrolix) MutateResponse(ctx context.Context, res data.Frames) (data.Frames, error) {
+	if collector := queryStatsCollectorFromContext(ctx); collector != nil {
+		attachQueryStats(res, collector.get())
+	}
+	columnTypes := columnTypesCollectorFromContext(ctx)
 	for _, frame := range res {
+		annotateTimeFields(frame, columnTypes.columnType)
+		if h.splitGeoPoints {
+			splitGeoPointFields(frame, columnTypes.columnType)
+		}
+		if h.widenFloat32 {
+			widenFloat32Fields(frame)
+		}
+		if h.expandTuples {
+			if err := expandTupleFields(frame, columnTypes.columnType); err != nil {
+				return res, err
+			}
+		}
+		if h.flattenJSON {
+			if err := flattenJSONFields(frame, columnTypes.columnType); err != nil {
+				return res, err
+			}
+		}
+		// after expansion and flattening, whose integer fields are converted as well
+		if safeIntegersFromContext(ctx, h.safeIntegers) {
+			convertUnsafeIntegerFields(frame)
+		}
 		if shouldConvertFields(frame.Meta.PreferredVisualization) {
 			if err := convertNullableJSONFields(frame); err != nil {
 				return res, err
@@ -669,12 +820,13 @@ func shouldConvertFields(visType data.VisType) bool {
 	return visType != data.VisTypeTrace && visType != data.VisTypeTable && visType != data.VisTypeLogs
 }
 
-// convertNullableJSONFields converts all FieldTypeNullableJSON fields in the given frame to string.
+// convertNullableJSONFields converts all FieldTypeNullableJSON fields, and FieldTypeJSON fields of tuples, in the
+// given frame to string.
 func convertNullableJSONFields(frame *data.Frame) error {
 	var convertedFields []*data.Field
 
 	for _, field := range frame.Fields {
-		if field.Type() == data.FieldTypeNullableJSON {
+		if field.Type() == data.FieldTypeNullableJSON || field.Type() == data.FieldTypeJSON {
 			newField, err := convertFieldToString(field)
 			if err != nil {
 				return err
@@ -689,6 +841,66 @@ func convertNullableJSONFields(frame *data.Frame) error {
 	return nil
 }
 
+// annotateTimeFields sets the precision and the timezone of the DateTime and DateTime64 columns of time fields in
+// the custom field config, as precision and timezone keys. Columns without declared timezone have the timezone their
+// values were read in, the one of the query head.
+func annotateTimeFields(frame *data.Frame, columnType func(name string) string) {
+	for _, field := range frame.Fields {
+		if field.Type() != data.FieldTypeTime && field.Type() != data.FieldTypeNullableTime {
+			continue
+		}
+		c, ok := converters.ParseTimeColumn(columnType(field.Name))
+		if !ok {
+			continue
+		}
+		for i := 0; i < field.Len() && c.Timezone == ""; i++ {
+			if t, ok := field.ConcreteAt(i); ok {
+				c.Timezone = t.(time.Time).Location().String()
+			}
+		}
+		if field.Config == nil {
+			field.Config = &data.FieldConfig{}
+		}
+		if field.Config.Custom == nil {
+			field.Config.Custom = map[string]interface{}{}
+		}
+		field.Config.Custom["precision"] = c.Precision
+		if c.Timezone != "" {
+			field.Config.Custom["timezone"] = c.Timezone
+		}
+	}
+}
+
+// widenFloat32Fields replaces Float32 and Nullable(Float32) fields of the frame with float64 fields.
+func widenFloat32Fields(frame *data.Frame) {
+	for i, field := range frame.Fields {
+		switch field.Type() {
+		case data.FieldTypeFloat32:
+			values := make([]float64, field.Len())
+			for j := range values {
+				values[j] = widenFloat32(field.At(j).(float32))
+			}
+			frame.Fields[i] = data.NewField(field.Name, field.Labels, values).SetConfig(field.Config)
+		case data.FieldTypeNullableFloat32:
+			values := make([]*float64, field.Len())
+			for j := range values {
+				if v := field.At(j).(*float32); v != nil {
+					f := widenFloat32(*v)
+					values[j] = &f
+				}
+			}
+			frame.Fields[i] = data.NewField(field.Name, field.Labels, values).SetConfig(field.Config)
+		}
+	}
+}
+
+// widenFloat32 converts v to the float64 with the same shortest decimal representation, so 0.1 stays 0.1 instead of
+// 0.10000000149011612.
+func widenFloat32(v float32) float64 {
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+	return f
+}
+
 // convertFieldToString creates a new field where JSON values are marshaled into string representations.
 func convertFieldToString(field *data.Field) (*data.Field, error) {
 	values := make([]*string, field.Len())
@@ -697,6 +909,9 @@ func convertFieldToString(field *data.Field) (*data.Field, error) {
 
 	for i := 0; i < field.Len(); i++ {
 		val, _ := field.At(i).(*json.RawMessage)
+		if raw, ok := field.At(i).(json.RawMessage); ok {
+			val = &raw
+		}
 		if val == nil {
 			newField.Set(i, nil)
 		} else {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/hydrolix/sqlds/v5/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -250,6 +251,12 @@ func TestSettings_ForwardHeaders(t *testing.T) {
 
 			ds := h.Settings(context.Background(), config)
 			assert.Equal(t, tt.wantForward, ds.ForwardHeaders)
+			if tt.wantForward {
+				assert.Equal(t, []string{closedPoolError}, ds.RetryOn)
+				assert.Equal(t, 1, ds.Retries)
+			} else {
+				assert.Empty(t, ds.RetryOn)
+			}
 		})
 	}
 }
@@ -922,3 +929,59 @@ func TestPanelIDString(t *testing.T) {
 		})
 	}
 }
+
+func TestMutateResponseWidensFloat32(t *testing.T) {
+	newFrame := func() *data.Frame {
+		return data.NewFrame("A",
+			data.NewField("ratio", nil, []float32{0.1, 2.5}),
+			data.NewField("nullable_ratio", nil, []*float32{nil, ptr(float32(0.3))}),
+			data.NewField("count", nil, []int64{1, 2}),
+		).SetMeta(&data.FrameMeta{})
+	}
+
+	h := NewHydrolix()
+	frames, err := h.MutateResponse(context.Background(), data.Frames{newFrame()})
+	assert.NoError(t, err)
+	assert.Equal(t, data.FieldTypeFloat32, frames[0].Fields[0].Type())
+
+	h.widenFloat32 = true
+	frames, err = h.MutateResponse(context.Background(), data.Frames{newFrame()})
+	assert.NoError(t, err)
+	fields := frames[0].Fields
+	assert.Equal(t, data.FieldTypeFloat64, fields[0].Type())
+	assert.Equal(t, 0.1, fields[0].At(0))
+	assert.Equal(t, 2.5, fields[0].At(1))
+	assert.Equal(t, data.FieldTypeNullableFloat64, fields[1].Type())
+	assert.Nil(t, fields[1].At(0))
+	assert.Equal(t, ptr(0.3), fields[1].At(1))
+	assert.Equal(t, data.FieldTypeInt64, fields[2].Type())
+}
+
+func TestMutateResponseAnnotatesTimeFields(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	value := time.Date(2025, 2, 11, 1, 1, 1, 123456000, tokyo)
+
+	ctx := withColumnTypesCollector(context.Background())
+	columnTypesCollectorFromContext(ctx).types = map[string]string{
+		"ts":     "DateTime64(6, 'Asia/Tokyo')",
+		"server": "Nullable(DateTime64(3))",
+		"empty":  "DateTime",
+	}
+	frame := data.NewFrame("A",
+		data.NewField("ts", nil, []time.Time{value}),
+		data.NewField("server", nil, []*time.Time{nil, &value}),
+		data.NewField("empty", nil, []time.Time{}),
+		data.NewField("plain", nil, []time.Time{value}),
+	).SetMeta(&data.FrameMeta{})
+
+	frames, err := NewHydrolix().MutateResponse(ctx, data.Frames{frame})
+	assert.NoError(t, err)
+	fields := frames[0].Fields
+	assert.Equal(t, map[string]interface{}{"precision": 6, "timezone": "Asia/Tokyo"}, fields[0].Config.Custom)
+	// columns without timezone are in the timezone of their values
+	assert.Equal(t, map[string]interface{}{"precision": 3, "timezone": "Asia/Tokyo"}, fields[1].Config.Custom)
+	assert.Equal(t, map[string]interface{}{"precision": 0}, fields[2].Config.Custom)
+	// fields of unknown columns aren't annotated
+	assert.Nil(t, fields[3].Config)
+}
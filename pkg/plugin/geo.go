@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"encoding/json"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Formats of Point columns in query results.
+const (
+	geoPointFormatLatLon  = "latlon"
+	geoPointFormatGeoJSON = "geojson"
+)
+
+// geometry is a GeoJSON geometry as returned by the converters of Point, Ring, Polygon and MultiPolygon columns.
+type geometry struct {
+	Type        string
+	Coordinates []float64
+}
+
+// geometryOf decodes a GeoJSON geometry, ok is false for any other value. Coordinates are only decoded for points.
+func geometryOf(raw json.RawMessage) (g geometry, ok bool) {
+	if jsonKind(raw) != jsonObject {
+		return g, false
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil || len(members) != 2 || members["coordinates"] == nil {
+		return g, false
+	}
+	if err := json.Unmarshal(members["type"], &g.Type); err != nil {
+		return g, false
+	}
+	switch g.Type {
+	case "Point":
+		if err := json.Unmarshal(members["coordinates"], &g.Coordinates); err != nil || len(g.Coordinates) != 2 {
+			return g, false
+		}
+	case "Polygon", "MultiPolygon":
+	default:
+		return g, false
+	}
+	return g, true
+}
+
+// splitGeoPointFields replaces the GeoJSON fields of Point columns with col.latitude and col.longitude fields. The
+// fields of the first Point column are displayed as latitude and longitude, the names the Geomap panel looks for to
+// locate rows in its Auto location mode. Columns are chosen by their type, JSON columns holding GeoJSON points and
+// other geometries are kept as GeoJSON.
+func splitGeoPointFields(frame *data.Frame, columnType func(name string) string) {
+	fields := make([]*data.Field, 0, len(frame.Fields))
+	located := false
+	for _, field := range frame.Fields {
+		if field.Type() != data.FieldTypeNullableJSON || columnType(field.Name) != "Point" {
+			fields = append(fields, field)
+			continue
+		}
+
+		latitude := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, field.Len())
+		latitude.Name = field.Name + ".latitude"
+		latitude.Labels = field.Labels
+		longitude := data.NewFieldFromFieldType(data.FieldTypeNullableFloat64, field.Len())
+		longitude.Name = field.Name + ".longitude"
+		longitude.Labels = field.Labels
+		for i := 0; i < field.Len(); i++ {
+			v, _ := field.At(i).(*json.RawMessage)
+			if v == nil {
+				continue
+			}
+			if g, ok := geometryOf(*v); ok {
+				// GeoJSON positions are longitude first
+				longitude.Set(i, &g.Coordinates[0])
+				latitude.Set(i, &g.Coordinates[1])
+			}
+		}
+		if !located {
+			latitude.Config = &data.FieldConfig{DisplayNameFromDS: "latitude"}
+			longitude.Config = &data.FieldConfig{DisplayNameFromDS: "longitude"}
+			located = true
+		}
+		fields = append(fields, latitude, longitude)
+	}
+	frame.Fields = fields
+}
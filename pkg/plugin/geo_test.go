@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitGeoPointFields(t *testing.T) {
+	polygon := `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,0]]]}`
+	frame := data.NewFrame("A",
+		data.NewField("location", nil, nullableJSONValues(`{"type":"Point","coordinates":[-73.98,40.75]}`, "")),
+		data.NewField("origin", nil, nullableJSONValues(`{"type":"Point","coordinates":[2.35,48.85]}`, `{"type":"Point","coordinates":[0,0]}`)),
+		data.NewField("area", nil, nullableJSONValues(polygon, "")),
+		data.NewField("payload", nil, nullableJSONValues(`{"type":"Point","coordinates":[1,2]}`, "")),
+	)
+	types := map[string]string{"location": "Point", "origin": "Point", "area": "Polygon", "payload": "JSON"}
+
+	splitGeoPointFields(frame, func(name string) string { return types[name] })
+
+	require.Len(t, frame.Fields, 6)
+	names := make([]string, len(frame.Fields))
+	for i, f := range frame.Fields {
+		names[i] = f.Name
+	}
+	// the JSON column holding GeoJSON points isn't a Point column
+	assert.Equal(t, []string{"location.latitude", "location.longitude", "origin.latitude", "origin.longitude", "area", "payload"}, names)
+
+	assert.Equal(t, ptr(40.75), frame.Fields[0].At(0))
+	assert.Equal(t, ptr(-73.98), frame.Fields[1].At(0))
+	assert.Nil(t, frame.Fields[0].At(1))
+	assert.Equal(t, "latitude", frame.Fields[0].Config.DisplayNameFromDS)
+	assert.Equal(t, "longitude", frame.Fields[1].Config.DisplayNameFromDS)
+
+	assert.Equal(t, ptr(48.85), frame.Fields[2].At(0))
+	assert.Equal(t, ptr(0.0), frame.Fields[3].At(1))
+	assert.Nil(t, frame.Fields[2].Config)
+
+	assert.Equal(t, data.FieldTypeNullableJSON, frame.Fields[4].Type())
+	assert.Equal(t, data.FieldTypeNullableJSON, frame.Fields[5].Type())
+}
+
+func TestMutateResponseGeoPointFormat(t *testing.T) {
+	newFrame := func() *data.Frame {
+		return data.NewFrame("A",
+			data.NewField("location", nil, nullableJSONValues(`{"type":"Point","coordinates":[-73.98,40.75]}`)),
+		).SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeTable})
+	}
+	ctx := withColumnTypesCollector(context.Background())
+	columnTypesCollectorFromContext(ctx).types = map[string]string{"location": "Point"}
+
+	h := NewHydrolix()
+	frames, err := h.MutateResponse(ctx, data.Frames{newFrame()})
+	require.NoError(t, err)
+	require.Len(t, frames[0].Fields, 2)
+	assert.Equal(t, "location.latitude", frames[0].Fields[0].Name)
+
+	h.splitGeoPoints = false
+	frames, err = h.MutateResponse(ctx, data.Frames{newFrame()})
+	require.NoError(t, err)
+	require.Len(t, frames[0].Fields, 1)
+	assert.Equal(t, "location", frames[0].Fields[0].Name)
+	assert.Equal(t, data.FieldTypeNullableJSON, frames[0].Fields[0].Type())
+}
+
+func TestFlattenJSONFieldsKeepsGeometries(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("area", nil, nullableJSONValues(`{"type":"MultiPolygon","coordinates":[[[[0,0],[10,0],[10,10],[0,0]]]]}`)),
+	)
+
+	require.NoError(t, flattenJSONFields(frame, func(string) string { return "MultiPolygon" }))
+
+	require.Len(t, frame.Fields, 1)
+	assert.Equal(t, "area", frame.Fields[0].Name)
+}
@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// Connection strategies used to pick a query head for new connections when the
+// datasource is configured with several hosts.
+const (
+	connectionStrategyInOrder    = "in_order"
+	connectionStrategyRoundRobin = "round_robin"
+	connectionStrategyRandom     = "random"
+)
+
+// connOpenStrategy maps the configured connection strategy to clickhouse-go's one.
+func (s hydrolixSettings) connOpenStrategy() clickhouse.ConnOpenStrategy {
+	switch s.ConnectionStrategy {
+	case connectionStrategyRoundRobin:
+		return clickhouse.ConnOpenRoundRobin
+	case connectionStrategyRandom:
+		return clickhouse.ConnOpenRandom
+	default:
+		return clickhouse.ConnOpenInOrder
+	}
+}
+
+// hostAddresses returns host:port addresses of the primary host followed by the
+// additional hosts. Additional hosts without a port use the primary port,
+// blank and duplicate entries are skipped.
+func hostAddresses(host string, port uint16, hosts []string) ([]string, error) {
+	defaultPort := strconv.Itoa(int(port))
+	addrs := []string{net.JoinHostPort(host, defaultPort)}
+	seen := map[string]bool{addrs[0]: true}
+	for _, h := range hosts {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		addr := h
+		if hostPart, portPart, err := net.SplitHostPort(h); err == nil {
+			if _, err := strconv.ParseUint(portPart, 10, 16); err != nil || hostPart == "" {
+				return nil, fmt.Errorf("invalid host %q", h)
+			}
+		} else {
+			addr = net.JoinHostPort(strings.Trim(h, "[]"), defaultPort)
+		}
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// hostStatus is the health of a single query head.
+type hostStatus struct {
+	Address string `json:"address"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// probeHosts pings every address of opts over a dedicated connection.
+func probeHosts(ctx context.Context, opts *clickhouse.Options) []hostStatus {
+	statuses := make([]hostStatus, len(opts.Addr))
+	var wg sync.WaitGroup
+	for i, addr := range opts.Addr {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = probeHost(ctx, opts, addr)
+		}()
+	}
+	wg.Wait()
+	return statuses
+}
+
+func probeHost(ctx context.Context, opts *clickhouse.Options, addr string) hostStatus {
+	o := *opts
+	o.Addr = []string{addr}
+	db := clickhouse.OpenDB(&o)
+	defer func() { _ = db.Close() }()
+
+	status := hostStatus{Address: addr, Healthy: true}
+	if err := db.PingContext(ctx); err != nil {
+		log.DefaultLogger.Warn("query head is unavailable", "address", addr, "err", err)
+		status.Healthy = false
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// healthyFirst orders addresses so healthy query heads are tried before the
+// unhealthy ones. Unhealthy heads are kept as the last resort, so a head that
+// recovers is still reachable by the pool. Only the in order strategy tries
+// addresses in order, the others keep the configured order.
+func healthyFirst(strategy clickhouse.ConnOpenStrategy, statuses []hostStatus) []string {
+	addrs := make([]string, 0, len(statuses))
+	if strategy != clickhouse.ConnOpenInOrder {
+		for _, s := range statuses {
+			addrs = append(addrs, s.Address)
+		}
+		return addrs
+	}
+	for _, s := range statuses {
+		if s.Healthy {
+			addrs = append(addrs, s.Address)
+		}
+	}
+	for _, s := range statuses {
+		if !s.Healthy {
+			addrs = append(addrs, s.Address)
+		}
+	}
+	return addrs
+}
+
+// queryHeads keeps the options of the last connection pool opened by
+// Hydrolix.Connect for a datasource with several hosts, so the health check
+// can probe its query heads.
+type queryHeads struct {
+	mu   sync.Mutex
+	opts *clickhouse.Options
+}
+
+// set keeps a copy of opts with its own address list, so later changes of
+// opts by the caller don't change the probed heads.
+func (q *queryHeads) set(opts *clickhouse.Options) {
+	o := *opts
+	o.Addr = slices.Clone(opts.Addr)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.opts = &o
+}
+
+// probe checks the query heads again, false if the datasource has a single host.
+func (q *queryHeads) probe(ctx context.Context) ([]hostStatus, bool) {
+	q.mu.Lock()
+	opts := q.opts
+	q.mu.Unlock()
+	if opts == nil {
+		return nil, false
+	}
+	if opts.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.DialTimeout)
+		defer cancel()
+	}
+	return probeHosts(ctx, opts), true
+}
@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostAddresses(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		hosts   []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "single host",
+			host: "hdx.example.com",
+			want: []string{"hdx.example.com:9440"},
+		},
+		{
+			name:  "additional hosts with and without port",
+			host:  "head-1",
+			hosts: []string{"head-2", "head-3:9000", " ", "head-1", "head-2:9440"},
+			want:  []string{"head-1:9440", "head-2:9440", "head-3:9000"},
+		},
+		{
+			name:  "ipv6",
+			host:  "::1",
+			hosts: []string{"[::2]", "[::3]:9000"},
+			want:  []string{"[::1]:9440", "[::2]:9440", "[::3]:9000"},
+		},
+		{
+			name:    "invalid port",
+			host:    "head-1",
+			hosts:   []string{"head-2:http"},
+			wantErr: `invalid host "head-2:http"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hostAddresses(tt.host, 9440, tt.hosts)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConnectionStrategySettings(t *testing.T) {
+	for jsonData, want := range map[string]clickhouse.ConnOpenStrategy{
+		`{}`:                                    clickhouse.ConnOpenInOrder,
+		`{"connectionStrategy": "in_order"}`:    clickhouse.ConnOpenInOrder,
+		`{"connectionStrategy": "round_robin"}`: clickhouse.ConnOpenRoundRobin,
+		`{"connectionStrategy": "random"}`:      clickhouse.ConnOpenRandom,
+	} {
+		s, err := parseHydrolixSettings(json.RawMessage(jsonData))
+		require.NoError(t, err)
+		assert.Equal(t, want, s.connOpenStrategy(), jsonData)
+	}
+
+	_, err := parseHydrolixSettings(json.RawMessage(`{"connectionStrategy": "fastest"}`))
+	assert.EqualError(t, err, `invalid connection strategy: "fastest"`)
+}
+
+func TestProbeHosts(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	statuses := probeHosts(ctx, &clickhouse.Options{Addr: []string{addr}, DialTimeout: time.Second})
+
+	require.Len(t, statuses, 1)
+	assert.Equal(t, addr, statuses[0].Address)
+	assert.False(t, statuses[0].Healthy)
+	assert.NotEmpty(t, statuses[0].Error)
+}
+
+func TestHealthyFirst(t *testing.T) {
+	statuses := []hostStatus{
+		{Address: "a:9000"},
+		{Address: "b:9000", Healthy: true},
+		{Address: "c:9000"},
+		{Address: "d:9000", Healthy: true},
+	}
+	assert.Equal(t, []string{"b:9000", "d:9000", "a:9000", "c:9000"}, healthyFirst(clickhouse.ConnOpenInOrder, statuses))
+	assert.Equal(t, []string{"a:9000", "b:9000", "c:9000", "d:9000"}, healthyFirst(clickhouse.ConnOpenRoundRobin, statuses))
+	assert.Equal(t, []string{"a:9000", "b:9000", "c:9000", "d:9000"}, healthyFirst(clickhouse.ConnOpenRandom, statuses))
+}
+
+func TestQueryHeadsSnapshot(t *testing.T) {
+	var heads queryHeads
+	opts := &clickhouse.Options{Addr: []string{"a:9000", "b:9000"}}
+	heads.set(opts)
+	opts.Addr[0] = "c:9000"
+	opts.Addr = append(opts.Addr, "d:9000")
+	assert.Equal(t, []string{"a:9000", "b:9000"}, heads.opts.Addr)
+}
@@ -0,0 +1,76 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// httpHeaderSecureValuePrefix prefixes the header name in the key of secure
+// header values in the datasource's secure json data.
+const httpHeaderSecureValuePrefix = "httpHeaderValue:"
+
+// httpHeader is a custom header sent with every HTTP request to query heads.
+type httpHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Secure marks headers whose value is stored in the secure json data.
+	Secure bool `json:"secure"`
+}
+
+// reservedHTTPHeaders are set by the plugin or the HTTP client and can't be
+// overridden by custom headers.
+var reservedHTTPHeaders = map[string]bool{
+	"Authorization":       true, // basic auth and forwarded OAuth identity
+	"X-Id-Token":          true, // forwarded OAuth ID token
+	"X-Grafana-Org-Id":    true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Host":                true,
+	"Connection":          true,
+	"Content-Type":        true,
+	"Content-Length":      true,
+	"Content-Encoding":    true,
+	"Accept-Encoding":     true,
+	"Transfer-Encoding":   true,
+	"User-Agent":          true,
+}
+
+// isReservedHTTPHeader reports whether name is reserved, X-ClickHouse-* headers
+// carry credentials and settings of the query.
+func isReservedHTTPHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	return reservedHTTPHeaders[name] || strings.HasPrefix(name, "X-Clickhouse-")
+}
+
+// customHTTPHeaders validates custom headers and resolves their values.
+func customHTTPHeaders(headers []httpHeader, secureJsonData map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(headers))
+	seen := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		name := strings.TrimSpace(h.Name)
+		if !httpguts.ValidHeaderFieldName(name) {
+			return nil, fmt.Errorf("invalid HTTP header name %q", h.Name)
+		}
+		if isReservedHTTPHeader(name) {
+			return nil, fmt.Errorf("invalid HTTP header %q: the header is reserved", name)
+		}
+		key := http.CanonicalHeaderKey(name)
+		if seen[key] {
+			return nil, fmt.Errorf("invalid HTTP header %q: the header is set more than once", name)
+		}
+		seen[key] = true
+
+		value := h.Value
+		if h.Secure {
+			value = secureJsonData[httpHeaderSecureValuePrefix+name]
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid HTTP header %q: the value contains invalid characters", name)
+		}
+		result[name] = value
+	}
+	return result, nil
+}
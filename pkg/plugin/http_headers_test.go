@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomHTTPHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []httpHeader
+		secure  map[string]string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "plain and secure values",
+			headers: []httpHeader{
+				{Name: "X-Tenant-Id", Value: "acme"},
+				{Name: "X-Api-Key", Value: "ignored", Secure: true},
+			},
+			secure: map[string]string{"httpHeaderValue:X-Api-Key": "s3cr3t"},
+			want:   map[string]string{"X-Tenant-Id": "acme", "X-Api-Key": "s3cr3t"},
+		},
+		{
+			name:    "no headers",
+			headers: nil,
+			want:    map[string]string{},
+		},
+		{
+			name:    "reserved header",
+			headers: []httpHeader{{Name: "authorization", Value: "Bearer x"}},
+			wantErr: `invalid HTTP header "authorization": the header is reserved`,
+		},
+		{
+			name:    "clickhouse header",
+			headers: []httpHeader{{Name: "X-ClickHouse-User", Value: "default"}},
+			wantErr: `invalid HTTP header "X-ClickHouse-User": the header is reserved`,
+		},
+		{
+			name:    "org id header",
+			headers: []httpHeader{{Name: OrgIdHeaderKey, Value: "2"}},
+			wantErr: `invalid HTTP header "X-Grafana-Org-Id": the header is reserved`,
+		},
+		{
+			name:    "duplicate header",
+			headers: []httpHeader{{Name: "X-Route", Value: "a"}, {Name: "x-route", Value: "b"}},
+			wantErr: `invalid HTTP header "x-route": the header is set more than once`,
+		},
+		{
+			name:    "invalid name",
+			headers: []httpHeader{{Name: "X Route", Value: "a"}},
+			wantErr: `invalid HTTP header name "X Route"`,
+		},
+		{
+			name:    "invalid value",
+			headers: []httpHeader{{Name: "X-Route", Value: "a\r\nAuthorization: x"}},
+			wantErr: `invalid HTTP header "X-Route": the value contains invalid characters`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := customHTTPHeaders(tt.headers, tt.secure)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
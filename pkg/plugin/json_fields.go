@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// flattenJSONFields replaces the fields of JSON and Object('json') columns with a field per path named after the
+// column and the path: col.a.b for {"a":{"b":1}}. Columns are chosen by their type, Map, Variant and Dynamic columns
+// holding objects are kept as is. Arrays are not flattened.
+func flattenJSONFields(frame *data.Frame, columnType func(name string) string) error {
+	fields := make([]*data.Field, 0, len(frame.Fields))
+	for _, field := range frame.Fields {
+		if field.Type() != data.FieldTypeNullableJSON || !isJSONColumn(columnType(field.Name)) || !hasOnlyObjects(field) {
+			fields = append(fields, field)
+			continue
+		}
+		flattened, err := flattenJSONField(field)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, flattened...)
+	}
+	frame.Fields = fields
+	return nil
+}
+
+// isJSONColumn reports whether chType is the type of JSON objects: JSON, JSON with parameters or Object('json').
+func isJSONColumn(chType string) bool {
+	return chType == "JSON" || strings.HasPrefix(chType, "JSON(") || strings.HasPrefix(chType, "Object(")
+}
+
+// hasOnlyObjects reports whether every non-null value of a FieldTypeNullableJSON field is a JSON object, and the
+// field has at least one value.
+func hasOnlyObjects(field *data.Field) bool {
+	found := false
+	for i := 0; i < field.Len(); i++ {
+		v, _ := field.At(i).(*json.RawMessage)
+		if v == nil || jsonKind(*v) == jsonNull {
+			continue
+		}
+		if bytes.TrimSpace(*v)[0] != '{' {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// flattenJSONField returns the fields of the paths of a JSON field, the field itself when its objects have no path.
+func flattenJSONField(field *data.Field) ([]*data.Field, error) {
+	paths := &elementValues{values: map[string][]json.RawMessage{}, rows: field.Len()}
+	for row := 0; row < field.Len(); row++ {
+		v, _ := field.At(row).(*json.RawMessage)
+		if v == nil || jsonKind(*v) == jsonNull {
+			continue
+		}
+		if err := flattenObject(paths, row, "", *v); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(paths.names) == 0 {
+		return []*data.Field{field}, nil
+	}
+	fields := make([]*data.Field, 0, len(paths.names))
+	for _, path := range paths.names {
+		f := elementField(field.Name+"."+path, paths.values[path])
+		f.Labels = field.Labels
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// flattenObject collects the values of an object and of its nested objects by path.
+func flattenObject(paths *elementValues, row int, prefix string, raw json.RawMessage) error {
+	members, err := objectValues(raw)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		path := prefix + m.name
+		if v := bytes.TrimSpace(m.value); len(v) > 0 && v[0] == '{' {
+			if err := flattenObject(paths, row, path+".", v); err != nil {
+				return err
+			}
+			continue
+		}
+		paths.set(row, path, m.value)
+	}
+	return nil
+}
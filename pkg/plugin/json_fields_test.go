@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nullableJSONValues(values ...string) []*json.RawMessage {
+	result := make([]*json.RawMessage, len(values))
+	for i, v := range values {
+		if v != "" {
+			raw := json.RawMessage(v)
+			result[i] = &raw
+		}
+	}
+	return result
+}
+
+func TestFlattenJSONFields(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("log", nil, nullableJSONValues(`{"level":"info","http":{"status":200,"tags":["a"]}}`, "", `{"level":"warn","http":{"status":503}}`)),
+		data.NewField("tags", nil, nullableJSONValues(`["a"]`, `{"b":1}`, "")),
+		data.NewField("n", nil, []int64{1, 2, 3}),
+	)
+	types := map[string]string{"log": "JSON", "tags": "Object('json')", "n": "Int64"}
+
+	require.NoError(t, flattenJSONFields(frame, func(name string) string { return types[name] }))
+
+	names := make([]string, len(frame.Fields))
+	for i, f := range frame.Fields {
+		names[i] = f.Name
+	}
+	assert.Equal(t, []string{"log.level", "log.http.status", "log.http.tags", "tags", "n"}, names)
+
+	level, status, tags := frame.Fields[0], frame.Fields[1], frame.Fields[2]
+	assert.Equal(t, data.FieldTypeNullableString, level.Type())
+	assert.Equal(t, []any{ptr("info"), (*string)(nil), ptr("warn")}, []any{level.At(0), level.At(1), level.At(2)})
+	assert.Equal(t, data.FieldTypeNullableFloat64, status.Type())
+	assert.Equal(t, ptr(503.0), status.At(2))
+	assert.Equal(t, data.FieldTypeNullableJSON, tags.Type())
+	assert.Equal(t, ptr(json.RawMessage(`["a"]`)), tags.At(0))
+	assert.Nil(t, tags.At(2))
+
+	// fields with values other than objects are kept
+	assert.Equal(t, data.FieldTypeNullableJSON, frame.Fields[3].Type())
+}
+
+func TestFlattenJSONFieldsWithoutObjects(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("empty", nil, nullableJSONValues()),
+		data.NewField("nulls", nil, nullableJSONValues("", "null")),
+		data.NewField("blank", nil, nullableJSONValues(`{}`, `{}`)),
+	)
+
+	require.NoError(t, flattenJSONFields(frame, func(string) string { return "JSON" }))
+
+	// fields without paths are kept rather than flattened to no field
+	require.Len(t, frame.Fields, 3)
+	assert.Equal(t, "empty", frame.Fields[0].Name)
+	assert.Equal(t, 0, frame.Fields[0].Len())
+	assert.Equal(t, "nulls", frame.Fields[1].Name)
+	assert.Equal(t, 2, frame.Fields[1].Len())
+	assert.Equal(t, "blank", frame.Fields[2].Name)
+	assert.Equal(t, ptr(json.RawMessage(`{}`)), frame.Fields[2].At(1))
+}
+
+func TestFlattenJSONFieldsByColumnType(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("attrs", nil, nullableJSONValues(`{"a":"1"}`)),
+		data.NewField("value", nil, nullableJSONValues(`{"a":1}`)),
+		data.NewField("log", nil, nullableJSONValues(`{"a":{"b":1}}`)),
+	)
+	types := map[string]string{"attrs": "Map(String, String)", "value": "Variant(String, JSON)", "log": "JSON(max_dynamic_paths=16)"}
+
+	require.NoError(t, flattenJSONFields(frame, func(name string) string { return types[name] }))
+
+	// only JSON columns are flattened
+	names := make([]string, len(frame.Fields))
+	for i, f := range frame.Fields {
+		names[i] = f.Name
+	}
+	assert.Equal(t, []string{"attrs", "value", "log.a.b"}, names)
+}
+
+func TestMutateResponseFlattensJSON(t *testing.T) {
+	newFrame := func() *data.Frame {
+		return data.NewFrame("A",
+			data.NewField("log", nil, nullableJSONValues(`{"level":"info","tags":["a"]}`)),
+		).SetMeta(&data.FrameMeta{})
+	}
+
+	h := NewHydrolix()
+	frames, err := h.MutateResponse(context.Background(), data.Frames{newFrame()})
+	require.NoError(t, err)
+	assert.Equal(t, ptr(`{"level":"info","tags":["a"]}`), frames[0].Fields[0].At(0))
+
+	h.flattenJSON = true
+	ctx := withColumnTypesCollector(context.Background())
+	columnTypesCollectorFromContext(ctx).types = map[string]string{"log": "JSON"}
+	frames, err = h.MutateResponse(ctx, data.Frames{newFrame()})
+	require.NoError(t, err)
+	require.Len(t, frames[0].Fields, 2)
+	assert.Equal(t, "log.level", frames[0].Fields[0].Name)
+	assert.Equal(t, ptr("info"), frames[0].Fields[0].At(0))
+	// remaining JSON fields are still converted to strings
+	assert.Equal(t, "log.tags", frames[0].Fields[1].Name)
+	assert.Equal(t, ptr(`["a"]`), frames[0].Fields[1].At(0))
+}
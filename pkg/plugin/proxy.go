@@ -0,0 +1,190 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// proxyPasswordKey is the key of the proxy password in the datasource's
+// secure json data.
+const proxyPasswordKey = "proxyPassword"
+
+// outboundProxy routes connections to query heads through an HTTP(S) or
+// SOCKS5 proxy. HTTP connections use it as the proxy of their transport,
+// native connections dial through it with dialContext.
+type outboundProxy struct {
+	proxyFunc func(*url.URL) (*url.URL, error)
+	timeout   time.Duration
+}
+
+// newOutboundProxy returns the proxy configured by the datasource, nil if the
+// proxy URL is empty. The username and password override credentials of the
+// proxy URL.
+func newOutboundProxy(s hydrolixSettings, password string, timeout time.Duration) (*outboundProxy, error) {
+	rawURL := strings.TrimSpace(s.ProxyURL)
+	if rawURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL: unsupported scheme %q, expected http, https, socks5 or socks5h", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL: missing host")
+	}
+	if s.ProxyUsername != "" {
+		u.User = url.UserPassword(s.ProxyUsername, password)
+	}
+
+	// httpproxy implements the NO_PROXY semantics: host names, domain
+	// suffixes, IP addresses and CIDRs with optional ports
+	cfg := &httpproxy.Config{
+		HTTPProxy:  u.String(),
+		HTTPSProxy: u.String(),
+		NoProxy:    strings.Join(s.NoProxy, ","),
+	}
+	return &outboundProxy{proxyFunc: cfg.ProxyFunc(), timeout: timeout}, nil
+}
+
+// transportProxy is the Proxy of HTTP transports.
+func (p *outboundProxy) transportProxy(req *http.Request) (*url.URL, error) {
+	return p.proxyFunc(req.URL)
+}
+
+// dialContext dials a native connection to addr through the proxy. TLS is
+// negotiated with the query head over the proxied connection since
+// clickhouse-go doesn't apply TLS to connections of a custom dialer.
+func (p *outboundProxy) dialContext(tlsConfig *tls.Config) func(ctx context.Context, addr string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		if p.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, p.timeout)
+			defer cancel()
+		}
+
+		proxyURL, err := p.proxyFunc(&url.URL{Scheme: "https", Host: addr})
+		if err != nil {
+			return nil, err
+		}
+		var conn net.Conn
+		switch {
+		case proxyURL == nil:
+			conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		case proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h":
+			conn, err = dialSOCKS5(ctx, proxyURL, addr)
+		default:
+			conn, err = dialHTTPConnect(ctx, proxyURL, addr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil {
+			return conn, nil
+		}
+
+		cfg := tlsConfig.Clone()
+		if cfg.ServerName == "" {
+			host, _, _ := net.SplitHostPort(addr)
+			cfg.ServerName = host
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+func dialSOCKS5(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+	}
+	dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, &net.Dialer{})
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("socks5 proxy %s: %w", proxyURL.Host, err)
+	}
+	return conn, nil
+}
+
+// dialHTTPConnect opens a tunnel to addr with the CONNECT method of an HTTP(S) proxy.
+func dialHTTPConnect(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	var conn net.Conn
+	var err error
+	if proxyURL.Scheme == "https" {
+		conn, err = (&tls.Dialer{Config: &tls.Config{ServerName: proxyURL.Hostname()}}).DialContext(ctx, "tcp", proxyAddr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", proxyAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the handshake is bound to ctx, the tunnel itself is not
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("http proxy %s: %w", proxyURL.Host, err)
+	}
+	// the query head doesn't send anything before the client's hello, so
+	// the buffered reader can't consume bytes of the tunnel
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("http proxy %s: %w", proxyURL.Host, err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("http proxy %s: CONNECT %s: %s", proxyURL.Host, addr, res.Status)
+	}
+	if !stop() {
+		_ = conn.Close()
+		return nil, fmt.Errorf("http proxy %s: %w", proxyURL.Host, ctx.Err())
+	}
+	return conn, nil
+}
@@ -0,0 +1,218 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// QueryStats holds the values of the X-HDX-Query-Stats trailer sent by
+// Hydrolix query heads. Fields are nil when the query head didn't report
+// the corresponding key; unknown keys are kept in Other.
+type QueryStats struct {
+	ExecTime      *float64          `json:"execTime,omitempty"`
+	RowsRead      *int64            `json:"rowsRead,omitempty"`
+	BytesRead     *int64            `json:"bytesRead,omitempty"`
+	HeadRowsRead  *int64            `json:"headRowsRead,omitempty"`
+	PeerRowsRead  *int64            `json:"peerRowsRead,omitempty"`
+	NumPartitions *int64            `json:"numPartitions,omitempty"`
+	NumPeers      *int64            `json:"numPeers,omitempty"`
+	ResultRows    *int64            `json:"resultRows,omitempty"`
+	QueryAttempts *int64            `json:"queryAttempts,omitempty"`
+	MemoryUsage   *int64            `json:"memoryUsage,omitempty"`
+	Other         map[string]string `json:"other,omitempty"`
+}
+
+// FrameMeta is the custom metadata attached to frames returned by Hydrolix
+// queries (data.FrameMeta.Custom).
+type FrameMeta struct {
+	QueryStats *QueryStats `json:"queryStats,omitempty"`
+}
+
+// parseQueryStats parses the plain-text trailer
+// "\nX-HDX-Query-Stats:{key=val ...}\n". The braces are optional. Returns
+// false when data is not a stats trailer.
+func parseQueryStats(trailer []byte) (*QueryStats, bool) {
+	trailer = bytes.TrimLeft(trailer, "\n")
+	prefix := statsPrefix[1:]
+	if !bytes.HasPrefix(trailer, prefix) {
+		return nil, false
+	}
+	line := strings.TrimSpace(string(trailer[len(prefix):]))
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "{"), "}")
+
+	stats := &QueryStats{}
+	for _, pair := range strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == ',' }) {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		if !stats.set(key, value) {
+			if stats.Other == nil {
+				stats.Other = make(map[string]string)
+			}
+			stats.Other[key] = value
+		}
+	}
+	return stats, true
+}
+
+// set assigns a known key, reports false for unknown keys or malformed values.
+func (s *QueryStats) set(key, value string) bool {
+	switch key {
+	case "exec_time":
+		return parseStatFloat(value, &s.ExecTime)
+	case "rows_read":
+		return parseStatInt(value, &s.RowsRead)
+	case "bytes_read":
+		return parseStatInt(value, &s.BytesRead)
+	case "head_rows_read":
+		return parseStatInt(value, &s.HeadRowsRead)
+	case "peer_rows_read":
+		return parseStatInt(value, &s.PeerRowsRead)
+	case "num_partitions":
+		return parseStatInt(value, &s.NumPartitions)
+	case "num_peers":
+		return parseStatInt(value, &s.NumPeers)
+	case "result_rows":
+		return parseStatInt(value, &s.ResultRows)
+	case "query_attempts":
+		return parseStatInt(value, &s.QueryAttempts)
+	case "memory_usage":
+		return parseStatInt(value, &s.MemoryUsage)
+	}
+	return false
+}
+
+func parseStatInt(value string, dst **int64) bool {
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	*dst = &v
+	return true
+}
+
+func parseStatFloat(value string, dst **float64) bool {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	*dst = &v
+	return true
+}
+
+// frameStats converts reported values into Query Inspector stats.
+func (s *QueryStats) frameStats() []data.QueryStat {
+	var stats []data.QueryStat
+	add := func(displayName, unit string, value float64) {
+		stats = append(stats, data.QueryStat{
+			FieldConfig: data.FieldConfig{DisplayName: displayName, Unit: unit},
+			Value:       value,
+		})
+	}
+	for _, f := range []struct {
+		displayName string
+		unit        string
+		value       *int64
+	}{
+		{"Rows read", "", s.RowsRead},
+		{"Bytes read", "decbytes", s.BytesRead},
+		{"Head rows read", "", s.HeadRowsRead},
+		{"Peer rows read", "", s.PeerRowsRead},
+		{"Partitions scanned", "", s.NumPartitions},
+		{"Peers", "", s.NumPeers},
+		{"Result rows", "", s.ResultRows},
+		{"Query attempts", "", s.QueryAttempts},
+		{"Memory usage", "decbytes", s.MemoryUsage},
+	} {
+		if f.value != nil {
+			add(f.displayName, f.unit, float64(*f.value))
+		}
+	}
+	if s.ExecTime != nil {
+		add("Execution time", "ms", *s.ExecTime)
+	}
+	return stats
+}
+
+// decodeStatsTrailer returns the plain-text stats line of a trailer found by
+// findStatsIndex. LZ4-framed trailers are decompressed.
+func decodeStatsTrailer(trailer []byte) ([]byte, bool) {
+	if bytes.HasPrefix(trailer, statsPrefix) {
+		return trailer, true
+	}
+	if len(trailer) < chFrameHeaderSize {
+		return nil, false
+	}
+	return decompressStatsFrame(trailer[16:])
+}
+
+// queryStatsCollector receives the stats trailer of a query's HTTP response.
+// MutateQuery stores it in the query context, statsStrippingTransport finds
+// it through the request context and MutateResponse attaches the collected
+// stats to the returned frames.
+type queryStatsCollector struct {
+	mu    sync.Mutex
+	stats *QueryStats
+}
+
+type queryStatsCtxKey struct{}
+
+func withQueryStatsCollector(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryStatsCtxKey{}, &queryStatsCollector{})
+}
+
+func queryStatsCollectorFromContext(ctx context.Context) *queryStatsCollector {
+	c, _ := ctx.Value(queryStatsCtxKey{}).(*queryStatsCollector)
+	return c
+}
+
+// collect parses the raw trailer stripped from a response body.
+func (c *queryStatsCollector) collect(trailer []byte) {
+	line, ok := decodeStatsTrailer(trailer)
+	if !ok {
+		return
+	}
+	stats, ok := parseQueryStats(line)
+	if !ok {
+		log.DefaultLogger.Debug("unable to parse query stats", "stats", string(line))
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats = stats
+}
+
+func (c *queryStatsCollector) get() *QueryStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// attachQueryStats adds query stats to frames' metadata.
+func attachQueryStats(frames data.Frames, stats *QueryStats) {
+	if stats == nil {
+		return
+	}
+	frameStats := stats.frameStats()
+	for _, frame := range frames {
+		if frame == nil {
+			continue
+		}
+		if frame.Meta == nil {
+			frame.Meta = &data.FrameMeta{}
+		}
+		frame.Meta.Stats = append(frame.Meta.Stats, frameStats...)
+		if meta, ok := frame.Meta.Custom.(*FrameMeta); ok {
+			meta.QueryStats = stats
+		} else if frame.Meta.Custom == nil {
+			frame.Meta.Custom = &FrameMeta{QueryStats: stats}
+		}
+	}
+}
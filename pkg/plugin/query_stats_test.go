@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestParseQueryStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		trailer string
+		want    *QueryStats
+		wantOK  bool
+	}{
+		{
+			name:    "plain key values",
+			trailer: "\nX-HDX-Query-Stats:exec_time=12.5 rows_read=100 bytes_read=2048 num_partitions=3\n",
+			want: &QueryStats{
+				ExecTime:      ptr(12.5),
+				RowsRead:      ptr(int64(100)),
+				BytesRead:     ptr(int64(2048)),
+				NumPartitions: ptr(int64(3)),
+			},
+			wantOK: true,
+		},
+		{
+			name:    "braces and unknown keys",
+			trailer: "\nX-HDX-Query-Stats:{result_rows=1 query_attempts=1 memory_usage=6306448 catalog_time=4}\n",
+			want: &QueryStats{
+				ResultRows:    ptr(int64(1)),
+				QueryAttempts: ptr(int64(1)),
+				MemoryUsage:   ptr(int64(6306448)),
+				Other:         map[string]string{"catalog_time": "4"},
+			},
+			wantOK: true,
+		},
+		{
+			name:    "malformed value is kept as other",
+			trailer: "\nX-HDX-Query-Stats:rows_read=many peer_rows_read=5",
+			want: &QueryStats{
+				PeerRowsRead: ptr(int64(5)),
+				Other:        map[string]string{"rows_read": "many"},
+			},
+			wantOK: true,
+		},
+		{
+			name:    "not a stats trailer",
+			trailer: "some data",
+			want:    nil,
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseQueryStats([]byte(tt.trailer))
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecodeStatsTrailer(t *testing.T) {
+	line := "\nX-HDX-Query-Stats:exec_time=0 result_rows=1\n"
+
+	got, ok := decodeStatsTrailer([]byte(line))
+	require.True(t, ok)
+	assert.Equal(t, line, string(got))
+
+	got, ok = decodeStatsTrailer(buildCHLZ4Frame([]byte(line)))
+	require.True(t, ok)
+	assert.Equal(t, line, string(got))
+
+	_, ok = decodeStatsTrailer([]byte("garbage"))
+	assert.False(t, ok)
+}
+
+func TestAttachQueryStats(t *testing.T) {
+	stats := &QueryStats{RowsRead: ptr(int64(10)), BytesRead: ptr(int64(1024)), ExecTime: ptr(1.5)}
+	frames := data.Frames{
+		data.NewFrame("A"),
+		data.NewFrame("B").SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeLogs}),
+	}
+
+	attachQueryStats(frames, stats)
+
+	for _, frame := range frames {
+		require.NotNil(t, frame.Meta)
+		assert.Equal(t, []data.QueryStat{
+			{FieldConfig: data.FieldConfig{DisplayName: "Rows read"}, Value: 10},
+			{FieldConfig: data.FieldConfig{DisplayName: "Bytes read", Unit: "decbytes"}, Value: 1024},
+			{FieldConfig: data.FieldConfig{DisplayName: "Execution time", Unit: "ms"}, Value: 1.5},
+		}, frame.Meta.Stats)
+		assert.Equal(t, &FrameMeta{QueryStats: stats}, frame.Meta.Custom)
+	}
+	assert.Equal(t, data.VisType(data.VisTypeLogs), frames[1].Meta.PreferredVisualization)
+}
+
+func TestStatsStrippingTransportCollectsStats(t *testing.T) {
+	for name, trailer := range map[string][]byte{
+		"plain text": []byte("\nX-HDX-Query-Stats:rows_read=42\n"),
+		"lz4 frame":  buildCHLZ4Frame([]byte("\nX-HDX-Query-Stats:rows_read=42\n")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(append([]byte("data"), trailer...))
+			}))
+			defer srv.Close()
+
+			ctx := withQueryStatsCollector(context.Background())
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+			require.NoError(t, err)
+
+			client := &http.Client{Transport: &statsStrippingTransport{next: http.DefaultTransport}}
+			res, err := client.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, "data", string(body))
+			stats := queryStatsCollectorFromContext(ctx).get()
+			require.NotNil(t, stats)
+			assert.Equal(t, ptr(int64(42)), stats.RowsRead)
+		})
+	}
+}
+
+func TestMutateResponseAttachesQueryStats(t *testing.T) {
+	ctx := withQueryStatsCollector(context.Background())
+	queryStatsCollectorFromContext(ctx).collect([]byte("\nX-HDX-Query-Stats:result_rows=7\n"))
+
+	frames, err := NewHydrolix().MutateResponse(ctx, data.Frames{
+		data.NewFrame("A").SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeTable}),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []data.QueryStat{
+		{FieldConfig: data.FieldConfig{DisplayName: "Result rows"}, Value: 7},
+	}, frames[0].Meta.Stats)
+}
@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// maxSafeInteger is the largest integer JavaScript numbers hold exactly, 2^53-1. Larger Int64 and UInt64 values are
+// rounded by the Grafana frontend.
+const maxSafeInteger = 1<<53 - 1
+
+type safeIntegersCtxKey struct{}
+
+// withSafeIntegers stores the safe integers mode of a query, overriding the mode of the datasource.
+func withSafeIntegers(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, safeIntegersCtxKey{}, enabled)
+}
+
+// safeIntegersFromContext returns the safe integers mode of the query, or the mode of the datasource when the query
+// doesn't set it.
+func safeIntegersFromContext(ctx context.Context, datasourceMode bool) bool {
+	if enabled, ok := ctx.Value(safeIntegersCtxKey{}).(bool); ok {
+		return enabled
+	}
+	return datasourceMode
+}
+
+// convertUnsafeIntegerFields replaces Int64 and UInt64 fields of the frame holding values beyond ±(2^53-1) with
+// string fields, so the frontend doesn't round them, and adds a notice per converted field. Fields whose values are
+// all in the safe range are kept as numbers.
+func convertUnsafeIntegerFields(frame *data.Frame) {
+	for i, field := range frame.Fields {
+		switch field.Type() {
+		case data.FieldTypeInt64, data.FieldTypeNullableInt64, data.FieldTypeUint64, data.FieldTypeNullableUint64:
+		default:
+			continue
+		}
+		if !hasUnsafeIntegers(field) {
+			continue
+		}
+
+		var converted *data.Field
+		if field.Nullable() {
+			values := make([]*string, field.Len())
+			for j := range values {
+				if v, ok := field.ConcreteAt(j); ok {
+					s := formatInteger(v)
+					values[j] = &s
+				}
+			}
+			converted = data.NewField(field.Name, field.Labels, values)
+		} else {
+			values := make([]string, field.Len())
+			for j := range values {
+				values[j] = formatInteger(field.At(j))
+			}
+			converted = data.NewField(field.Name, field.Labels, values)
+		}
+		frame.Fields[i] = converted.SetConfig(field.Config)
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityInfo,
+			Text: fmt.Sprintf("Field %s has values beyond ±2^53-1, which lose precision as numbers in the browser, "+
+				"so it is returned as strings.", field.Name),
+		})
+	}
+}
+
+// hasUnsafeIntegers reports whether an Int64 or UInt64 field holds a value beyond ±(2^53-1).
+func hasUnsafeIntegers(field *data.Field) bool {
+	for i := 0; i < field.Len(); i++ {
+		v, ok := field.ConcreteAt(i)
+		if !ok {
+			continue
+		}
+		switch n := v.(type) {
+		case int64:
+			if n > maxSafeInteger || n < -maxSafeInteger {
+				return true
+			}
+		case uint64:
+			if n > maxSafeInteger {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func formatInteger(v interface{}) string {
+	switch n := v.(type) {
+	case int64:
+		return strconv.FormatInt(n, 10)
+	case uint64:
+		return strconv.FormatUint(n, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertUnsafeIntegerFields(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("id", nil, []uint64{1, 18446744073709551615}),
+		data.NewField("hash", nil, []*int64{nil, ptr(int64(-9007199254740992))}),
+		data.NewField("count", nil, []int64{9007199254740991, -9007199254740991}),
+		data.NewField("nullable_count", nil, []*uint64{nil, ptr(uint64(1))}),
+	)
+
+	convertUnsafeIntegerFields(frame)
+
+	assert.Equal(t, data.FieldTypeString, frame.Fields[0].Type())
+	assert.Equal(t, "1", frame.Fields[0].At(0))
+	assert.Equal(t, "18446744073709551615", frame.Fields[0].At(1))
+	assert.Equal(t, data.FieldTypeNullableString, frame.Fields[1].Type())
+	assert.Nil(t, frame.Fields[1].At(0))
+	assert.Equal(t, ptr("-9007199254740992"), frame.Fields[1].At(1))
+	assert.Equal(t, data.FieldTypeInt64, frame.Fields[2].Type())
+	assert.Equal(t, data.FieldTypeNullableUint64, frame.Fields[3].Type())
+
+	require.Len(t, frame.Meta.Notices, 2)
+	assert.Equal(t, data.NoticeSeverityInfo, frame.Meta.Notices[0].Severity)
+	assert.Contains(t, frame.Meta.Notices[0].Text, "Field id")
+	assert.Contains(t, frame.Meta.Notices[1].Text, "Field hash")
+}
+
+func TestMutateResponseSafeIntegers(t *testing.T) {
+	newFrames := func() data.Frames {
+		return data.Frames{data.NewFrame("A",
+			data.NewField("id", nil, []uint64{18446744073709551615}),
+		).SetMeta(&data.FrameMeta{})}
+	}
+	queryContext := func(h *Hydrolix, query string) context.Context {
+		ctx, _ := h.MutateQuery(context.Background(), backend.DataQuery{JSON: json.RawMessage(query)})
+		return ctx
+	}
+
+	h := NewHydrolix()
+	frames, err := h.MutateResponse(queryContext(h, `{}`), newFrames())
+	require.NoError(t, err)
+	assert.Equal(t, data.FieldTypeUint64, frames[0].Fields[0].Type())
+
+	frames, err = h.MutateResponse(queryContext(h, `{"safeIntegers": true}`), newFrames())
+	require.NoError(t, err)
+	assert.Equal(t, data.FieldTypeString, frames[0].Fields[0].Type())
+
+	h.safeIntegers = true
+	frames, err = h.MutateResponse(queryContext(h, `{}`), newFrames())
+	require.NoError(t, err)
+	assert.Equal(t, data.FieldTypeString, frames[0].Fields[0].Type())
+
+	frames, err = h.MutateResponse(queryContext(h, `{"safeIntegers": false}`), newFrames())
+	require.NoError(t, err)
+	assert.Equal(t, data.FieldTypeUint64, frames[0].Fields[0].Type())
+}
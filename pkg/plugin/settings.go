@@ -0,0 +1,253 @@
+package plugin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hydrolix/plugin/pkg/converters"
+)
+
+// streamingResultSetting is the Hydrolix setting that makes query heads stream
+// result blocks as soon as they are ready instead of buffering the whole result.
+const streamingResultSetting = "hdx_query_streaming_result"
+
+// Connection pool defaults applied when the datasource doesn't configure them.
+const (
+	defaultMaxOpenConns    = 25
+	defaultMaxIdleConns    = 5
+	defaultConnMaxIdleTime = 2 * time.Minute
+	defaultConnMaxLifetime = 2 * time.Minute
+)
+
+// defaultSchemaCacheTTL is the lifetime of cached schema resource results.
+const defaultSchemaCacheTTL = 5 * time.Minute
+
+// hydrolixSettings carries Hydrolix-only connection options read directly from
+// the raw DataSourceInstanceSettings.JSONData (the sqlds-provided
+// PluginSettings struct doesn't model these knobs).
+type hydrolixSettings struct {
+	// StreamingResult enables hdx_query_streaming_result for HTTP connections.
+	StreamingResult bool `json:"streamingResult"`
+
+	// Hosts lists additional query heads as "host" or "host:port", the port of
+	// the primary host is used when omitted.
+	Hosts []string `json:"hosts"`
+	// ConnectionStrategy selects the query head for new connections, one of
+	// in_order (default), round_robin and random.
+	ConnectionStrategy string `json:"connectionStrategy"`
+
+	// Compression is the compression method of query results, one of none,
+	// lz4, zstd and, for HTTP only, gzip, deflate and br. Defaults to lz4 for
+	// the native protocol and none for HTTP.
+	Compression string `json:"compression"`
+	// CompressionLevel is the compression level of zstd, gzip, deflate and br,
+	// 0 means the query head's default.
+	CompressionLevel int `json:"compressionLevel"`
+
+	// MaxUserPools limits connection pools kept for forwarded OAuth
+	// identities, 0 means default.
+	MaxUserPools int `json:"maxUserPools"`
+	// UserPoolTTL is the lifetime in seconds of connection pools of forwarded
+	// OAuth identities whose token has no expiration, 0 means default.
+	UserPoolTTL int `json:"userPoolTTL"`
+
+	// ProxyURL is the http, https, socks5 or socks5h URL of the proxy used to
+	// connect to query heads, empty for direct connections.
+	ProxyURL string `json:"proxyUrl"`
+	// ProxyUsername overrides the username of ProxyURL, the password is read
+	// from the secure json data.
+	ProxyUsername string `json:"proxyUsername"`
+	// NoProxy lists hosts, domains, IP addresses and CIDRs connected directly.
+	NoProxy []string `json:"noProxy"`
+
+	// HTTPHeaders are sent with every HTTP request, reserved headers are rejected.
+	HTTPHeaders []httpHeader `json:"httpHeaders"`
+
+	// DecimalAsString returns decimals as strings keeping every digit instead
+	// of float64.
+	DecimalAsString bool `json:"decimalAsString"`
+	// WideIntegersAsFloat returns 128 and 256-bit integers as float64 instead
+	// of strings, values above 2^53 are rounded.
+	WideIntegersAsFloat bool `json:"wideIntegersAsFloat"`
+
+	// WidenFloat32 converts Float32 fields of query results to float64.
+	WidenFloat32 bool `json:"widenFloat32"`
+
+	// ExpandTuples replaces Tuple and Nested fields of query results with a
+	// field per element instead of a JSON field.
+	ExpandTuples bool `json:"expandTuples"`
+
+	// FlattenJSON replaces JSON fields of query results whose values are
+	// objects with a field per path.
+	FlattenJSON bool `json:"flattenJSON"`
+
+	// GeoPointFormat is the format of Point columns in query results, one of
+	// latlon (default) for latitude and longitude fields and geojson for a
+	// GeoJSON field.
+	GeoPointFormat string `json:"geoPointFormat"`
+
+	// SafeIntegers returns Int64 and UInt64 fields of query results with
+	// values beyond ±(2^53-1) as strings, queries may override it.
+	SafeIntegers bool `json:"safeIntegers"`
+
+	// TypeMappings override the conversion of the column types they match,
+	// e.g. UInt64 as strings or Map as labels.
+	TypeMappings []converters.TypeMapping `json:"typeMappings"`
+
+	// TLSServerName overrides the server name verified in certificates of
+	// secure connections.
+	TLSServerName string `json:"tlsServerName"`
+
+	// MaxOpenConns limits connections opened by the datasource, 0 means default.
+	MaxOpenConns int `json:"maxOpenConns"`
+	// MaxIdleConns limits idle connections kept by the datasource, 0 means default.
+	MaxIdleConns int `json:"maxIdleConns"`
+	// ConnMaxIdleTime is the maximum idle time of a connection in seconds, 0 means default.
+	ConnMaxIdleTime int `json:"connMaxIdleTime"`
+	// ConnMaxLifetime is the maximum lifetime of a connection in seconds, 0 means default.
+	ConnMaxLifetime int `json:"connMaxLifetime"`
+
+	// SchemaCacheTTL is the lifetime in seconds of databases, tables and
+	// columns cached by schema resources, 0 means default and -1 disables
+	// the cache.
+	SchemaCacheTTL int `json:"schemaCacheTTL"`
+}
+
+// parseHydrolixSettings reads hydrolixSettings from datasource's jsonData.
+func parseHydrolixSettings(jsonData json.RawMessage) (hydrolixSettings, error) {
+	var s hydrolixSettings
+	if len(jsonData) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(jsonData, &s); err != nil {
+		return s, err
+	}
+	return s, s.validate()
+}
+
+// validate checks option values and their consistency.
+func (s hydrolixSettings) validate() error {
+	for _, v := range []struct {
+		name  string
+		value int
+	}{
+		{"max open connections", s.MaxOpenConns},
+		{"max idle connections", s.MaxIdleConns},
+		{"connection max idle time", s.ConnMaxIdleTime},
+		{"connection max lifetime", s.ConnMaxLifetime},
+		{"max user connection pools", s.MaxUserPools},
+		{"user connection pool TTL", s.UserPoolTTL},
+	} {
+		if v.value < 0 {
+			return fmt.Errorf("invalid connection pool settings: %s must not be negative", v.name)
+		}
+	}
+	switch s.ConnectionStrategy {
+	case "", connectionStrategyInOrder, connectionStrategyRoundRobin, connectionStrategyRandom:
+	default:
+		return fmt.Errorf("invalid connection strategy: %q", s.ConnectionStrategy)
+	}
+	switch s.GeoPointFormat {
+	case "", geoPointFormatLatLon, geoPointFormatGeoJSON:
+	default:
+		return fmt.Errorf("invalid geo point format: %q", s.GeoPointFormat)
+	}
+	if s.SchemaCacheTTL < -1 {
+		return fmt.Errorf("invalid schema cache TTL: %d", s.SchemaCacheTTL)
+	}
+	for _, m := range s.TypeMappings {
+		if err := m.Validate(); err != nil {
+			return fmt.Errorf("invalid type mapping: %w", err)
+		}
+	}
+	if s.maxIdleConns() > s.maxOpenConns() {
+		return fmt.Errorf("invalid connection pool settings: max idle connections (%d) must not exceed max open connections (%d)",
+			s.maxIdleConns(), s.maxOpenConns())
+	}
+	return nil
+}
+
+func (s hydrolixSettings) maxOpenConns() int {
+	if s.MaxOpenConns == 0 {
+		return defaultMaxOpenConns
+	}
+	return s.MaxOpenConns
+}
+
+func (s hydrolixSettings) maxIdleConns() int {
+	if s.MaxIdleConns == 0 {
+		return min(defaultMaxIdleConns, s.maxOpenConns())
+	}
+	return s.MaxIdleConns
+}
+
+func (s hydrolixSettings) connMaxIdleTime() time.Duration {
+	if s.ConnMaxIdleTime == 0 {
+		return defaultConnMaxIdleTime
+	}
+	return time.Duration(s.ConnMaxIdleTime) * time.Second
+}
+
+func (s hydrolixSettings) connMaxLifetime() time.Duration {
+	if s.ConnMaxLifetime == 0 {
+		return defaultConnMaxLifetime
+	}
+	return time.Duration(s.ConnMaxLifetime) * time.Second
+}
+
+func (s hydrolixSettings) maxUserPools() int {
+	if s.MaxUserPools == 0 {
+		return defaultMaxUserPools
+	}
+	return s.MaxUserPools
+}
+
+func (s hydrolixSettings) userPoolTTL() time.Duration {
+	if s.UserPoolTTL == 0 {
+		return defaultUserPoolTTL
+	}
+	return time.Duration(s.UserPoolTTL) * time.Second
+}
+
+// applyConnectionPool configures db's connection pool.
+func (s hydrolixSettings) applyConnectionPool(db *sql.DB) {
+	db.SetMaxOpenConns(s.maxOpenConns())
+	db.SetMaxIdleConns(s.maxIdleConns())
+	db.SetConnMaxIdleTime(s.connMaxIdleTime())
+	db.SetConnMaxLifetime(s.connMaxLifetime())
+}
+
+// httpQuerySettings returns ClickHouse settings applied to every HTTP query.
+func httpQuerySettings(s hydrolixSettings) map[string]any {
+	settings := map[string]any{
+		// native format
+		"hdx_query_output_format": "Native",
+	}
+	if s.StreamingResult {
+		settings[streamingResultSetting] = "true"
+	}
+	return settings
+}
+
+// schemaCacheTTL returns the lifetime of cached schema resource results, 0 when the cache is disabled.
+func (s hydrolixSettings) schemaCacheTTL() time.Duration {
+	switch s.SchemaCacheTTL {
+	case 0:
+		return defaultSchemaCacheTTL
+	case -1:
+		return 0
+	default:
+		return time.Duration(s.SchemaCacheTTL) * time.Second
+	}
+}
+
+// converterOptions returns the options of data type converters.
+func (s hydrolixSettings) converterOptions() converters.Options {
+	return converters.Options{
+		DecimalAsString:     s.DecimalAsString,
+		WideIntegersAsFloat: s.WideIntegersAsFloat,
+		TypeMappings:        s.TypeMappings,
+	}
+}
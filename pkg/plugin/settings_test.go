@@ -0,0 +1,266 @@
+package plugin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/hydrolix/plugin/pkg/converters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpQuerySettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		jsonData json.RawMessage
+		want     map[string]any
+	}{
+		{
+			name:     "empty json data",
+			jsonData: nil,
+			want:     map[string]any{"hdx_query_output_format": "Native"},
+		},
+		{
+			name:     "streaming result disabled",
+			jsonData: json.RawMessage(`{"streamingResult": false}`),
+			want:     map[string]any{"hdx_query_output_format": "Native"},
+		},
+		{
+			name:     "streaming result enabled",
+			jsonData: json.RawMessage(`{"streamingResult": true}`),
+			want: map[string]any{
+				"hdx_query_output_format":    "Native",
+				"hdx_query_streaming_result": "true",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseHydrolixSettings(tt.jsonData)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, httpQuerySettings(s))
+		})
+	}
+}
+
+func TestConnectionPoolSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		jsonData json.RawMessage
+		wantErr  string
+		want     sql.DBStats
+	}{
+		{
+			name:     "defaults",
+			jsonData: json.RawMessage(`{}`),
+			want:     sql.DBStats{MaxOpenConnections: 25},
+		},
+		{
+			name:     "custom values",
+			jsonData: json.RawMessage(`{"maxOpenConns": 50, "maxIdleConns": 10, "connMaxIdleTime": 30, "connMaxLifetime": 600}`),
+			want:     sql.DBStats{MaxOpenConnections: 50},
+		},
+		{
+			name:     "default idle connections are limited by max open connections",
+			jsonData: json.RawMessage(`{"maxOpenConns": 2}`),
+			want:     sql.DBStats{MaxOpenConnections: 2},
+		},
+		{
+			name:     "negative value",
+			jsonData: json.RawMessage(`{"connMaxLifetime": -1}`),
+			wantErr:  "invalid connection pool settings: connection max lifetime must not be negative",
+		},
+		{
+			name:     "idle connections exceed open connections",
+			jsonData: json.RawMessage(`{"maxOpenConns": 5, "maxIdleConns": 10}`),
+			wantErr:  "invalid connection pool settings: max idle connections (10) must not exceed max open connections (5)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseHydrolixSettings(tt.jsonData)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			db := clickhouse.OpenDB(&clickhouse.Options{Addr: []string{"localhost:9000"}})
+			defer db.Close()
+			s.applyConnectionPool(db)
+			assert.Equal(t, tt.want, db.Stats())
+		})
+	}
+}
+
+func TestCompressionSettings(t *testing.T) {
+	tests := []struct {
+		name         string
+		jsonData     string
+		protocol     clickhouse.Protocol
+		want         *clickhouse.Compression
+		wantSettings map[string]any
+		wantErr      string
+	}{
+		{
+			name:         "native default",
+			jsonData:     `{}`,
+			protocol:     clickhouse.Native,
+			want:         &clickhouse.Compression{Method: clickhouse.CompressionLZ4},
+			wantSettings: map[string]any{},
+		},
+		{
+			name:         "http default",
+			jsonData:     `{}`,
+			protocol:     clickhouse.HTTP,
+			want:         &clickhouse.Compression{Method: clickhouse.CompressionNone},
+			wantSettings: map[string]any{},
+		},
+		{
+			name:     "zstd with level",
+			jsonData: `{"compression": "zstd", "compressionLevel": 6}`,
+			protocol: clickhouse.Native,
+			want:     &clickhouse.Compression{Method: clickhouse.CompressionZSTD, Level: 6},
+			wantSettings: map[string]any{
+				"network_compression_method":     "ZSTD",
+				"network_zstd_compression_level": "6",
+			},
+		},
+		{
+			name:     "gzip default level",
+			jsonData: `{"compression": "gzip"}`,
+			protocol: clickhouse.HTTP,
+			want:     &clickhouse.Compression{Method: clickhouse.CompressionGZIP, Level: 3},
+			wantSettings: map[string]any{
+				"enable_http_compression": "1",
+			},
+		},
+		{
+			name:     "br with level",
+			jsonData: `{"compression": "br", "compressionLevel": 9}`,
+			protocol: clickhouse.HTTP,
+			want:     &clickhouse.Compression{Method: clickhouse.CompressionBrotli, Level: 9},
+			wantSettings: map[string]any{
+				"enable_http_compression":     "1",
+				"http_zlib_compression_level": "9",
+			},
+		},
+		{
+			name:     "http compression with native protocol",
+			jsonData: `{"compression": "deflate"}`,
+			protocol: clickhouse.Native,
+			wantErr:  "invalid compression: deflate is supported by the HTTP protocol only",
+		},
+		{
+			name:     "lz4 with level",
+			jsonData: `{"compression": "lz4", "compressionLevel": 3}`,
+			protocol: clickhouse.Native,
+			wantErr:  "invalid compression level: lz4 doesn't support levels",
+		},
+		{
+			name:     "level out of range",
+			jsonData: `{"compression": "gzip", "compressionLevel": 10}`,
+			protocol: clickhouse.HTTP,
+			wantErr:  "invalid compression level: 10, gzip supports levels 1-9",
+		},
+		{
+			name:     "unknown method",
+			jsonData: `{"compression": "snappy"}`,
+			protocol: clickhouse.HTTP,
+			wantErr:  `invalid compression: "snappy"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseHydrolixSettings(json.RawMessage(tt.jsonData))
+			require.NoError(t, err)
+			got, settings, err := s.compression(tt.protocol)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantSettings, settings)
+		})
+	}
+}
+
+func TestTypeMappingSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		jsonData json.RawMessage
+		wantErr  string
+	}{
+		{
+			name:     "valid mappings",
+			jsonData: json.RawMessage(`{"typeMappings": [{"typeRegex": "^UInt64$", "fieldType": "string"}, {"typeRegex": "^Map\\(", "fieldType": "string", "mode": "labels"}]}`),
+		},
+		{
+			name:     "missing regex",
+			jsonData: json.RawMessage(`{"typeMappings": [{"fieldType": "string"}]}`),
+			wantErr:  "invalid type mapping: type regex is required",
+		},
+		{
+			name:     "malformed regex",
+			jsonData: json.RawMessage(`{"typeMappings": [{"typeRegex": "^Map(", "fieldType": "string"}]}`),
+			wantErr:  "invalid type mapping: invalid type regex \"^Map(\": error parsing regexp: missing closing ): `^Map(`",
+		},
+		{
+			name:     "unknown field type",
+			jsonData: json.RawMessage(`{"typeMappings": [{"typeRegex": "^UInt64$", "fieldType": "time"}]}`),
+			wantErr:  `invalid type mapping: invalid field type "time" of type regex "^UInt64$"`,
+		},
+		{
+			name:     "labels of numbers",
+			jsonData: json.RawMessage(`{"typeMappings": [{"typeRegex": "^Map\\(", "fieldType": "number", "mode": "labels"}]}`),
+			wantErr:  `invalid type mapping: mode "labels" of type regex "^Map\\(" requires the string field type`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseHydrolixSettings(tt.jsonData)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			list := converters.New(s.converterOptions())
+			assert.Equal(t, "TypeMapping(^UInt64$)", list[0].Name)
+			assert.Equal(t, `TypeMapping(^Map\()`, list[1].Name)
+		})
+	}
+}
+
+func TestSchemaCacheTTLSettings(t *testing.T) {
+	for jsonData, want := range map[string]time.Duration{
+		`{}`:                     5 * time.Minute,
+		`{"schemaCacheTTL": 30}`: 30 * time.Second,
+		`{"schemaCacheTTL": -1}`: 0,
+	} {
+		s, err := parseHydrolixSettings(json.RawMessage(jsonData))
+		require.NoError(t, err)
+		assert.Equal(t, want, s.schemaCacheTTL(), jsonData)
+	}
+
+	_, err := parseHydrolixSettings(json.RawMessage(`{"schemaCacheTTL": -2}`))
+	assert.EqualError(t, err, "invalid schema cache TTL: -2")
+}
+
+func TestGeoPointFormatSettings(t *testing.T) {
+	for _, jsonData := range []string{`{}`, `{"geoPointFormat": "latlon"}`, `{"geoPointFormat": "geojson"}`} {
+		_, err := parseHydrolixSettings(json.RawMessage(jsonData))
+		assert.NoError(t, err, jsonData)
+	}
+
+	_, err := parseHydrolixSettings(json.RawMessage(`{"geoPointFormat": "wkt"}`))
+	assert.EqualError(t, err, `invalid geo point format: "wkt"`)
+}
@@ -2,10 +2,16 @@ package plugin
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"encoding/binary"
+	"fmt"
 	"io"
+	"net/http"
 
+	"github.com/andybalholm/brotli"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 )
 
@@ -15,7 +21,7 @@ import (
 var statsPrefix = []byte("\nX-HDX-Query-Stats:")
 
 // maxStatsLineSize is the maximum expected size of the trailing stats
-// data. For LZ4-framed bodies this is the full ClickHouse frame
+// data. For framed bodies this is the full ClickHouse frame
 // (CityHash128 16 + header 9 + payload); for plain-text bodies it is
 // the raw stats line. We hold back this many bytes at the tail of the
 // stream so findStatsIndex has the entire last frame/line in view at
@@ -54,6 +60,9 @@ type statsStrippingReader struct {
 	trail int    // boundary of data safe to return to caller
 	end   int    // one past last byte of upstream data
 	err   error  // terminal error from upstream
+
+	// onTrailer, if set, receives the stripped trailer bytes at EOF.
+	onTrailer func([]byte)
 }
 
 func newStatsStrippingReader(r io.ReadCloser) *statsStrippingReader {
@@ -101,6 +110,9 @@ func (s *statsStrippingReader) Read(p []byte) (int, error) {
 		if err == io.EOF {
 			s.err = err
 			s.trail = s.start + findStatsIndex(s.buf[s.start:s.end])
+			if s.onTrailer != nil && s.trail < s.end {
+				s.onTrailer(s.buf[s.trail:s.end])
+			}
 		} else if err != nil {
 			s.err = err
 			s.trail = s.end
@@ -118,20 +130,95 @@ func (s *statsStrippingReader) Close() error {
 	return s.r.Close()
 }
 
+// statsStrippingTransport wraps every HTTP response body with
+// statsStrippingReader so the trailing X-HDX-Query-Stats block never
+// reaches the ClickHouse block decoder. Bodies without the trailer pass
+// through unchanged, so the wrapping is safe regardless of whether
+// hdx_query_streaming_result is enabled for the datasource or per query.
+// The stripped trailer is handed to the queryStatsCollector found in the
+// request context, if any.
+type statsStrippingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *statsStrippingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil || res.Body == nil || res.Body == http.NoBody {
+		return res, err
+	}
+	// The trailer is part of the HTTP-compressed stream, decode it here so
+	// the trailer can be found. clickhouse-go reads the body as is once
+	// Content-Encoding is removed.
+	if encoding := res.Header.Get("Content-Encoding"); !res.Uncompressed && isContentEncodingSupported(encoding) {
+		res.Body = &contentDecodingReader{body: res.Body, encoding: encoding}
+		res.Header.Del("Content-Encoding")
+		res.Uncompressed = true
+	}
+	body := newStatsStrippingReader(res.Body)
+	if collector := queryStatsCollectorFromContext(req.Context()); collector != nil {
+		body.onTrailer = collector.collect
+	}
+	res.Body = body
+	// the stripped body is shorter than the one announced by the server
+	res.ContentLength = -1
+	res.Header.Del("Content-Length")
+	return res, nil
+}
+
+func isContentEncodingSupported(encoding string) bool {
+	switch encoding {
+	case compressionGZIP, compressionDeflate, compressionBrotli:
+		return true
+	}
+	return false
+}
+
+// contentDecodingReader decodes a gzip, deflate (zlib) or br encoded HTTP
+// response body. The decoder is created on the first Read, so reading the
+// stream header doesn't block RoundTrip.
+type contentDecodingReader struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.Reader
+}
+
+func (d *contentDecodingReader) Read(p []byte) (int, error) {
+	if d.r == nil {
+		var err error
+		switch d.encoding {
+		case compressionGZIP:
+			d.r, err = gzip.NewReader(d.body)
+		case compressionDeflate:
+			d.r, err = zlib.NewReader(d.body)
+		case compressionBrotli:
+			d.r = brotli.NewReader(d.body)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("decode %s response: %w", d.encoding, err)
+		}
+	}
+	return d.r.Read(p)
+}
+
+func (d *contentDecodingReader) Close() error {
+	return d.body.Close()
+}
+
 // findStatsIndex returns the index within data where the trailing
 // X-HDX-Query-Stats data begins, or len(data) if no trailer is found.
 // The Hydrolix server bug (when hdx_query_streaming_result=1) appends
 // a spurious "\nX-HDX-Query-Stats:...\n" block as the last frame of
-// the body — LZ4-framed when the response is Native+LZ4, plain text
-// when the format is non-binary.
+// the body — framed with the result's compression method (LZ4, ZSTD or
+// none) when the response is Native with compress=1, plain text
+// otherwise.
 //
-// Frame walk runs first: a successful LZ4 decompression to the marker
+// Frame walk runs first: a successful decompression to the marker
 // is a definitive match and avoids the false-positive risk of finding
 // the literal prefix inside compressed payload bytes. Plain-text
-// search runs as a fallback for non-LZ4 bodies.
+// search runs as a fallback for unframed bodies.
 func findStatsIndex(data []byte) int {
 	if idx := findStatsFrameIndex(data); idx < len(data) {
-		log.DefaultLogger.Debug("found lz4 frame of stats info", "pos", idx)
+		log.DefaultLogger.Debug("found compressed frame of stats info", "pos", idx)
 		return idx
 	}
 	idx := findStatsTextIndex(data)
@@ -164,7 +251,7 @@ func findStatsTextIndex(data []byte) int {
 }
 
 // findStatsFrameIndex walks backward through data looking for a
-// ClickHouse LZ4-compressed frame whose payload begins with the
+// ClickHouse compressed frame whose payload begins with the
 // X-HDX-Query-Stats marker. Returns the frame start (including the
 // 16-byte CityHash128 checksum) or len(data) if no match.
 //
@@ -172,8 +259,8 @@ func findStatsTextIndex(data []byte) int {
 // p (the method byte), accept only if data[p] is a valid frame method
 // byte and the LE u32 compressed_size at p+1 equals the distance from
 // p to the end (i.e. the compressed size matches the tail exactly).
-// Verify by LZ4-decompressing the payload and confirming it begins
-// with "\nX-HDX-Query-Stats:". On no match, fall through to
+// Verify by decompressing the payload and confirming it begins with
+// "\nX-HDX-Query-Stats:". On no match, fall through to
 // len(data) — this also makes the eventual server fix a silent no-op
 // for the client.
 func findStatsFrameIndex(data []byte) int {
@@ -208,25 +295,51 @@ func findStatsFrameIndex(data []byte) int {
 // verifyStatsFrame reports whether the given ClickHouse compressed
 // frame (starting at the method byte) decompresses to a payload that
 // begins with the X-HDX-Query-Stats marker.
-//
-// Only LZ4 (method 0x82) is verified — the streaming-mode bug is
-// LZ4-specific, and refusing to strip ZSTD/NONE tails is the safe
-// conservative behavior.
 func verifyStatsFrame(frame []byte) bool {
+	_, ok := decompressStatsFrame(frame)
+	return ok
+}
+
+// zstdDecoder decompresses ZSTD stats frames. DecodeAll is safe for
+// concurrent use.
+var zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(chMaxDecompressLen))
+
+// decompressStatsFrame decompresses a ClickHouse LZ4, ZSTD or uncompressed
+// frame (starting at the method byte) and returns its payload if it begins
+// with the X-HDX-Query-Stats marker. The marker check keeps coincidental
+// header matches inside other frames' payload from being stripped.
+func decompressStatsFrame(frame []byte) ([]byte, bool) {
 	if len(frame) < 9 {
-		return false
-	}
-	if frame[0] != chMethodLZ4 {
-		return false
+		return nil, false
 	}
 	decompSize := binary.LittleEndian.Uint32(frame[5:9])
 	if decompSize < uint32(len(statsPrefix)) || decompSize > chMaxDecompressLen {
-		return false
+		return nil, false
+	}
+	payload := frame[9:]
+
+	var dst []byte
+	switch frame[0] {
+	case chMethodLZ4:
+		dst = make([]byte, decompSize)
+		n, err := lz4.UncompressBlock(payload, dst)
+		if err != nil {
+			return nil, false
+		}
+		dst = dst[:n]
+	case chMethodZSTD:
+		var err error
+		dst, err = zstdDecoder.DecodeAll(payload, make([]byte, 0, decompSize))
+		if err != nil {
+			return nil, false
+		}
+	case chMethodNone:
+		dst = payload
+	default:
+		return nil, false
 	}
-	dst := make([]byte, decompSize)
-	n, err := lz4.UncompressBlock(frame[9:], dst)
-	if err != nil || n < len(statsPrefix) {
-		return false
+	if len(dst) != int(decompSize) || !bytes.HasPrefix(dst, statsPrefix) {
+		return nil, false
 	}
-	return bytes.HasPrefix(dst[:n], statsPrefix)
+	return dst, true
 }
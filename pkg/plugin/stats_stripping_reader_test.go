@@ -2,10 +2,16 @@ package plugin
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"encoding/binary"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 )
 
@@ -476,3 +482,139 @@ func TestStatsStrippingReaderWithLZ4Frame(t *testing.T) {
 		t.Errorf("got %d bytes, want %d bytes (real data without stats frame)", len(got), len(realData))
 	}
 }
+
+func TestStatsStrippingTransport(t *testing.T) {
+	t.Parallel()
+
+	body := "lots of clickhouse data here"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body + "\nX-HDX-Query-Stats:exec_time=0 result_rows=1\n"))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &statsStrippingTransport{next: http.DefaultTransport}}
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.ContentLength != -1 {
+		t.Errorf("got content length %d, want -1", res.ContentLength)
+	}
+	got, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != body {
+		t.Errorf("got %q, want %q", string(got), body)
+	}
+}
+
+// buildCHFrame builds a ClickHouse frame compressed with ZSTD or stored
+// uncompressed (method 0x02), see buildCHLZ4Frame for the layout.
+func buildCHFrame(method byte, text []byte) []byte {
+	payload := text
+	if method == chMethodZSTD {
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			panic(err)
+		}
+		payload = enc.EncodeAll(text, nil)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 16))
+	buf.WriteByte(method)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(9+len(payload)))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(text)))
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+func TestStatsStrippingReaderWithFrameMethods(t *testing.T) {
+	t.Parallel()
+
+	statsText := []byte("\nX-HDX-Query-Stats:exec_time=0 result_rows=1 query_attempts=1 memory_usage=6306448\n")
+	realData := bytes.Repeat([]byte{0xDD}, 200)
+
+	for name, method := range map[string]byte{"zstd": chMethodZSTD, "none": chMethodNone} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var trailer []byte
+			r := newStatsStrippingReader(io.NopCloser(bytes.NewReader(append(realData, buildCHFrame(method, statsText)...))))
+			r.onTrailer = func(b []byte) { trailer = append([]byte(nil), b...) }
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, realData) {
+				t.Errorf("got %d bytes, want %d bytes (real data without stats frame)", len(got), len(realData))
+			}
+			line, ok := decodeStatsTrailer(trailer)
+			if !ok || !bytes.Equal(line, statsText) {
+				t.Errorf("got trailer %q, want %q", line, statsText)
+			}
+		})
+	}
+
+	t.Run("zstd non-stats frame is kept", func(t *testing.T) {
+		t.Parallel()
+		input := append(realData, buildCHFrame(chMethodZSTD, bytes.Repeat([]byte("not stats "), 10))...)
+		got, err := io.ReadAll(newStatsStrippingReader(io.NopCloser(bytes.NewReader(input))))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(got, input) {
+			t.Errorf("got %d bytes, want %d bytes", len(got), len(input))
+		}
+	})
+}
+
+func TestStatsStrippingTransportWithContentEncoding(t *testing.T) {
+	t.Parallel()
+
+	body := "lots of clickhouse data here"
+	encoders := map[string]func(w io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"br":      func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+	}
+	for encoding, newEncoder := range encoders {
+		t.Run(encoding, func(t *testing.T) {
+			t.Parallel()
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", encoding)
+				enc := newEncoder(w)
+				_, _ = enc.Write([]byte(body + "\nX-HDX-Query-Stats:exec_time=0 result_rows=1\n"))
+				_ = enc.Close()
+			}))
+			defer srv.Close()
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// clickhouse-go requests the encoding itself, so the transport doesn't decode it
+			req.Header.Set("Accept-Encoding", encoding)
+			client := &http.Client{Transport: &statsStrippingTransport{next: http.DefaultTransport}}
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer res.Body.Close()
+
+			if enc := res.Header.Get("Content-Encoding"); enc != "" {
+				t.Errorf("got Content-Encoding %q, want none", enc)
+			}
+			got, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != body {
+				t.Errorf("got %q, want %q", string(got), body)
+			}
+		})
+	}
+}
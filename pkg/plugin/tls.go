@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Keys of the TLS certificates in the datasource's secure json data.
+const (
+	tlsCACertKey     = "tlsCACert"
+	tlsClientCertKey = "tlsClientCert"
+	tlsClientKeyKey  = "tlsClientKey"
+)
+
+// newTLSConfig builds the TLS configuration of secure connections. The CA
+// bundle replaces the system roots when provided; the client certificate
+// and key enable mutual TLS and must be provided together.
+func newTLSConfig(skipVerify bool, serverName string, secureJsonData map[string]string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: skipVerify,
+		ServerName:         strings.TrimSpace(serverName),
+	}
+
+	if caCert := strings.TrimSpace(secureJsonData[tlsCACertKey]); caCert != "" {
+		pool, err := parseCACertificates(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS CA certificate: %w", err)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	clientCert := strings.TrimSpace(secureJsonData[tlsClientCertKey])
+	clientKey := strings.TrimSpace(secureJsonData[tlsClientKeyKey])
+	switch {
+	case clientCert == "" && clientKey == "":
+	case clientCert == "":
+		return nil, errors.New("invalid TLS client certificate: client key is set but client certificate is missing")
+	case clientKey == "":
+		return nil, errors.New("invalid TLS client certificate: client certificate is set but client key is missing")
+	default:
+		cert, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// parseCACertificates reads every certificate of a PEM bundle. Unlike
+// x509.CertPool.AppendCertsFromPEM, it reports which block is malformed.
+func parseCACertificates(bundle string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	rest := []byte(bundle)
+	for n := 1; ; n++ {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			if n == 1 {
+				return nil, errors.New("no PEM encoded certificate found")
+			}
+			if len(strings.TrimSpace(string(rest))) > 0 {
+				return nil, fmt.Errorf("unexpected data after certificate %d", n-1)
+			}
+			return pool, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("PEM block %d is %q, expected CERTIFICATE", n, block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %d: %w", n, err)
+		}
+		pool.AddCert(cert)
+	}
+}
@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM string
+	keyPEM  string
+}
+
+// newTestCert issues a certificate signed by parent, or a self-signed CA when parent is nil.
+func newTestCert(t *testing.T, parent *testCert, name string, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	ca := newTestCert(t, nil, "ca", x509.ExtKeyUsageAny)
+	client := newTestCert(t, ca, "client", x509.ExtKeyUsageClientAuth)
+
+	tests := []struct {
+		name    string
+		secure  map[string]string
+		wantErr string
+	}{
+		{
+			name:    "ca is not pem",
+			secure:  map[string]string{tlsCACertKey: "not a certificate"},
+			wantErr: "invalid TLS CA certificate: no PEM encoded certificate found",
+		},
+		{
+			name:    "ca is a private key",
+			secure:  map[string]string{tlsCACertKey: client.keyPEM},
+			wantErr: `invalid TLS CA certificate: PEM block 1 is "EC PRIVATE KEY", expected CERTIFICATE`,
+		},
+		{
+			name:    "garbage after ca",
+			secure:  map[string]string{tlsCACertKey: ca.certPEM + "garbage"},
+			wantErr: "invalid TLS CA certificate: unexpected data after certificate 1",
+		},
+		{
+			name:    "client key without certificate",
+			secure:  map[string]string{tlsClientKeyKey: client.keyPEM},
+			wantErr: "invalid TLS client certificate: client key is set but client certificate is missing",
+		},
+		{
+			name:    "client certificate without key",
+			secure:  map[string]string{tlsClientCertKey: client.certPEM},
+			wantErr: "invalid TLS client certificate: client certificate is set but client key is missing",
+		},
+		{
+			name:    "client key doesn't match certificate",
+			secure:  map[string]string{tlsClientCertKey: client.certPEM, tlsClientKeyKey: ca.keyPEM},
+			wantErr: "invalid TLS client certificate: tls: private key does not match public key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTLSConfig(false, "", tt.secure)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewTLSConfigMutualTLS(t *testing.T) {
+	ca := newTestCert(t, nil, "ca", x509.ExtKeyUsageAny)
+	server := newTestCert(t, ca, "hdx.internal", x509.ExtKeyUsageServerAuth)
+	client := newTestCert(t, ca, "grafana", x509.ExtKeyUsageClientAuth)
+
+	serverCert, err := tls.X509KeyPair([]byte(server.certPEM), []byte(server.keyPEM))
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(tlsConfig *tls.Config) (string, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		res, err := client.Get(srv.URL)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		buf := make([]byte, 64)
+		n, _ := res.Body.Read(buf)
+		return string(buf[:n]), nil
+	}
+
+	tlsConfig, err := newTLSConfig(false, "hdx.internal", map[string]string{
+		tlsCACertKey:     ca.certPEM,
+		tlsClientCertKey: client.certPEM,
+		tlsClientKeyKey:  client.keyPEM,
+	})
+	require.NoError(t, err)
+	got, err := get(tlsConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "grafana", got)
+
+	// server certificate is not trusted without the CA
+	tlsConfig, err = newTLSConfig(false, "hdx.internal", map[string]string{
+		tlsClientCertKey: client.certPEM,
+		tlsClientKeyKey:  client.keyPEM,
+	})
+	require.NoError(t, err)
+	_, err = get(tlsConfig)
+	assert.Error(t, err)
+
+	// server name doesn't match the certificate
+	tlsConfig, err = newTLSConfig(false, "other.internal", map[string]string{
+		tlsCACertKey:     ca.certPEM,
+		tlsClientCertKey: client.certPEM,
+		tlsClientKeyKey:  client.keyPEM,
+	})
+	require.NoError(t, err)
+	_, err = get(tlsConfig)
+	assert.Error(t, err)
+}
@@ -0,0 +1,513 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// expandTupleFields replaces Tuple and Nested fields of the frame, the fields of type FieldTypeJSON, with a field per
+// element named after the column and the element: col.a and col.b for Tuple(a String, b UInt8), col.1 and col.2 for
+// unnamed tuples. Elements of Nested columns become JSON arrays of the element values. Elements and their field types
+// are read from the column type, or from the values when columnType doesn't know the column.
+func expandTupleFields(frame *data.Frame, columnType func(name string) string) error {
+	fields := make([]*data.Field, 0, len(frame.Fields))
+	for _, field := range frame.Fields {
+		if field.Type() != data.FieldTypeJSON {
+			fields = append(fields, field)
+			continue
+		}
+		expanded, err := expandTupleField(field, columnType(field.Name))
+		if err != nil {
+			return err
+		}
+		fields = append(fields, expanded...)
+	}
+	frame.Fields = fields
+	return nil
+}
+
+// elementValues collects the values of tuple elements or JSON paths of a field in the order they first appear.
+type elementValues struct {
+	names  []string
+	values map[string][]json.RawMessage
+	rows   int
+}
+
+func (e *elementValues) set(row int, name string, value json.RawMessage) {
+	values, ok := e.values[name]
+	if !ok {
+		e.names = append(e.names, name)
+		values = make([]json.RawMessage, e.rows)
+		e.values[name] = values
+	}
+	values[row] = value
+}
+
+func expandTupleField(field *data.Field, chType string) ([]*data.Field, error) {
+	if elements, nested, ok := tupleElements(chType); ok {
+		return expandTypedTupleField(field, elements, nested)
+	}
+
+	elements := &elementValues{values: map[string][]json.RawMessage{}, rows: field.Len()}
+	for row := 0; row < field.Len(); row++ {
+		raw, _ := field.At(row).(json.RawMessage)
+		values, err := tupleValues(raw)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			elements.set(row, v.name, v.value)
+		}
+	}
+	if len(elements.names) == 0 {
+		// without values, elements are unknown
+		return []*data.Field{field}, nil
+	}
+
+	fields := make([]*data.Field, 0, len(elements.names))
+	for _, name := range elements.names {
+		f := elementField(field.Name+"."+name, elements.values[name])
+		f.Labels = field.Labels
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// expandTypedTupleField expands a tuple field to the elements of its column type, so columns without rows keep their
+// elements and elements keep the type of their values.
+func expandTypedTupleField(field *data.Field, elements []tupleElement, nested bool) ([]*data.Field, error) {
+	values := make([][]json.RawMessage, len(elements))
+	for i := range values {
+		values[i] = make([]json.RawMessage, field.Len())
+	}
+	for row := 0; row < field.Len(); row++ {
+		raw, _ := field.At(row).(json.RawMessage)
+		if jsonKind(raw) == jsonNull {
+			continue
+		}
+		var rowValues []json.RawMessage
+		var err error
+		if nested {
+			rowValues, err = nestedElementValues(raw, elements)
+		} else {
+			rowValues, err = tupleElementValues(raw, elements)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range rowValues {
+			values[i][row] = v
+		}
+	}
+
+	fields := make([]*data.Field, len(elements))
+	for i, e := range elements {
+		kind := jsonObject
+		if !nested {
+			kind = typeKind(e.chType)
+		}
+		fields[i] = newElementField(field.Name+"."+e.name, kind, values[i])
+		fields[i].Labels = field.Labels
+	}
+	return fields, nil
+}
+
+// tupleElementValues returns the values of the elements of a tuple, a JSON object for named tuples and a JSON array
+// for unnamed ones.
+func tupleElementValues(raw json.RawMessage, elements []tupleElement) ([]json.RawMessage, error) {
+	values := make([]json.RawMessage, len(elements))
+	if bytes.TrimSpace(raw)[0] == '{' {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, err
+		}
+		for i, e := range elements {
+			values[i] = object[e.name]
+		}
+		return values, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	copy(values, items)
+	return values, nil
+}
+
+// nestedElementValues returns the values of the elements of an array of tuples, the value of each element is the array
+// of its values in every nested row.
+func nestedElementValues(raw json.RawMessage, elements []tupleElement) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	nested := make([][]json.RawMessage, len(elements))
+	for i := range nested {
+		nested[i] = make([]json.RawMessage, len(items))
+	}
+	for j, item := range items {
+		if jsonKind(item) == jsonNull {
+			continue
+		}
+		itemValues, err := tupleElementValues(item, elements)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range itemValues {
+			nested[i][j] = v
+		}
+	}
+	values := make([]json.RawMessage, len(elements))
+	for i := range nested {
+		for j, v := range nested[i] {
+			if v == nil {
+				nested[i][j] = json.RawMessage("null")
+			}
+		}
+		b, err := json.Marshal(nested[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = b
+	}
+	return values, nil
+}
+
+// tupleElement is an element of a Tuple type, elements of unnamed tuples are named after their position
+type tupleElement struct {
+	name   string
+	chType string
+}
+
+// tupleElements returns the elements of a Tuple type or, for nested, of an Array(Tuple) type. ok is false for other
+// types.
+func tupleElements(chType string) (elements []tupleElement, nested bool, ok bool) {
+	if inner, found := typeArgs(chType, "Array"); found {
+		chType, nested = inner, true
+	}
+	args, found := typeArgs(chType, "Tuple")
+	if !found {
+		return nil, false, false
+	}
+	for i, arg := range splitTypeArgs(args) {
+		e := tupleElement{name: strconv.Itoa(i + 1), chType: arg}
+		if name, elementType, named := splitElementName(arg); named {
+			e.name, e.chType = name, elementType
+		}
+		elements = append(elements, e)
+	}
+	return elements, nested, len(elements) > 0
+}
+
+// typeArgs returns the arguments of a parametric type, Tuple(a String) for instance
+func typeArgs(chType, name string) (string, bool) {
+	chType = strings.TrimSpace(chType)
+	if !strings.HasPrefix(chType, name+"(") || !strings.HasSuffix(chType, ")") {
+		return "", false
+	}
+	return chType[len(name)+1 : len(chType)-1], true
+}
+
+// splitTypeArgs splits the arguments of a type at its top level commas, commas of nested types and quoted strings,
+// like Enum8('a,b' = 1), don't split arguments.
+func splitTypeArgs(args string) []string {
+	var result []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(args); i++ {
+		c := args[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			result = append(result, strings.TrimSpace(args[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(args[start:]); last != "" {
+		result = append(result, last)
+	}
+	return result
+}
+
+// splitElementName splits a named tuple element, e.g. a String or `a b` String, into its name and type
+func splitElementName(element string) (name, chType string, named bool) {
+	if strings.HasPrefix(element, "`") {
+		end := strings.Index(element[1:], "`")
+		if end < 0 {
+			return "", "", false
+		}
+		return element[1 : end+1], strings.TrimSpace(element[end+2:]), true
+	}
+	i := strings.IndexAny(element, " (")
+	if i < 0 || element[i] != ' ' {
+		return "", "", false
+	}
+	return element[:i], strings.TrimSpace(element[i+1:]), true
+}
+
+// typeKind returns the kind of the field of values of a ClickHouse type. Int64 and UInt64 values are kept as integers,
+// float64 doesn't hold them beyond 2^53, and wider integers as strings.
+func typeKind(chType string) int {
+	for _, wrapper := range []string{"Nullable", "LowCardinality"} {
+		if inner, ok := typeArgs(chType, wrapper); ok {
+			chType = inner
+		}
+	}
+	switch {
+	case chType == "Int64":
+		return jsonInt64
+	case chType == "UInt64":
+		return jsonUint64
+	case chType == "Bool":
+		return jsonBool
+	case strings.HasSuffix(chType, "128"), strings.HasSuffix(chType, "256"):
+		return jsonString
+	case strings.HasPrefix(chType, "Int"), strings.HasPrefix(chType, "UInt"), strings.HasPrefix(chType, "Float"),
+		chType == "BFloat16":
+		return jsonNumber
+	}
+	name, _, _ := strings.Cut(chType, "(")
+	if slices.Contains(compositeTypes, name) {
+		return jsonObject
+	}
+	return jsonString
+}
+
+// compositeTypes are the types whose values are JSON arrays or objects
+var compositeTypes = []string{"Array", "Map", "Tuple", "Nested", "JSON", "Object", "Variant", "Dynamic", "Point",
+	"Ring", "LineString", "MultiLineString", "Polygon", "MultiPolygon"}
+
+type tupleValue struct {
+	name  string
+	value json.RawMessage
+}
+
+// tupleValues splits a tuple into its elements. A JSON array of objects is a Nested value, the value of each element
+// is the array of its values in every nested row.
+func tupleValues(raw json.RawMessage) ([]tupleValue, error) {
+	if jsonKind(raw) == jsonNull {
+		return nil, nil
+	}
+	if bytes.TrimSpace(raw)[0] == '{' {
+		return objectValues(raw)
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	if !allObjects(items) {
+		values := make([]tupleValue, len(items))
+		for i, item := range items {
+			values[i] = tupleValue{name: strconv.Itoa(i + 1), value: item}
+		}
+		return values, nil
+	}
+
+	// Nested
+	var names []string
+	nested := map[string][]json.RawMessage{}
+	for i, item := range items {
+		values, err := objectValues(item)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			if _, ok := nested[v.name]; !ok {
+				names = append(names, v.name)
+				nested[v.name] = make([]json.RawMessage, len(items))
+			}
+			nested[v.name][i] = v.value
+		}
+	}
+	values := make([]tupleValue, 0, len(names))
+	for _, name := range names {
+		for i, v := range nested[name] {
+			if v == nil {
+				nested[name][i] = json.RawMessage("null")
+			}
+		}
+		b, err := json.Marshal(nested[name])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, tupleValue{name: name, value: b})
+	}
+	return values, nil
+}
+
+// allObjects reports whether items are the rows of a Nested value.
+func allObjects(items []json.RawMessage) bool {
+	for _, item := range items {
+		if item = bytes.TrimSpace(item); len(item) == 0 || item[0] != '{' {
+			return false
+		}
+	}
+	return len(items) > 0
+}
+
+// objectValues reads the members of a JSON object in their order.
+func objectValues(raw json.RawMessage) ([]tupleValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var values []tupleValue
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, tupleValue{name: key.(string), value: value})
+	}
+	return values, nil
+}
+
+// elementField creates the field of a tuple element or JSON path. Elements whose values are all numbers, strings or
+// booleans become fields of that type, other elements stay JSON. Integers beyond 2^53 are kept as int64 or uint64.
+func elementField(name string, values []json.RawMessage) *data.Field {
+	kind := jsonNull
+	for _, v := range values {
+		switch k := jsonKind(v); {
+		case k == jsonNull:
+		case kind == jsonNull:
+			kind = k
+		case k != kind:
+			kind = jsonObject
+		}
+	}
+	if kind == jsonNumber {
+		kind = integerKind(values)
+	}
+	return newElementField(name, kind, values)
+}
+
+// integerKind returns jsonInt64 or jsonUint64 for integer values of which some are beyond the float64 precision, and
+// jsonNumber otherwise.
+func integerKind(values []json.RawMessage) int {
+	unsafe, signed, unsigned := false, true, true
+	for _, v := range values {
+		if jsonKind(v) == jsonNull {
+			continue
+		}
+		i, intErr := strconv.ParseInt(string(v), 10, 64)
+		u, uintErr := strconv.ParseUint(string(v), 10, 64)
+		if intErr != nil && uintErr != nil {
+			return jsonNumber
+		}
+		signed = signed && intErr == nil
+		unsigned = unsigned && uintErr == nil
+		unsafe = unsafe || intErr == nil && (i > maxSafeInteger || i < -maxSafeInteger) || uintErr == nil && u > maxSafeInteger
+	}
+	switch {
+	case !unsafe:
+		return jsonNumber
+	case signed:
+		return jsonInt64
+	case unsigned:
+		return jsonUint64
+	default:
+		return jsonNumber
+	}
+}
+
+// newElementField creates the field of the values converted to the kind, values which don't convert are null.
+func newElementField(name string, kind int, values []json.RawMessage) *data.Field {
+	switch kind {
+	case jsonNumber:
+		return data.NewField(name, nil, convertValues(values, func(v json.RawMessage) (float64, error) {
+			return strconv.ParseFloat(string(v), 64)
+		}))
+	case jsonInt64:
+		return data.NewField(name, nil, convertValues(values, func(v json.RawMessage) (int64, error) {
+			return strconv.ParseInt(string(v), 10, 64)
+		}))
+	case jsonUint64:
+		return data.NewField(name, nil, convertValues(values, func(v json.RawMessage) (uint64, error) {
+			return strconv.ParseUint(string(v), 10, 64)
+		}))
+	case jsonString:
+		return data.NewField(name, nil, convertValues(values, func(v json.RawMessage) (s string, err error) {
+			if jsonKind(v) == jsonNumber {
+				// e.g. Int128 values, marshalled as numbers
+				return string(bytes.TrimSpace(v)), nil
+			}
+			return s, json.Unmarshal(v, &s)
+		}))
+	case jsonBool:
+		return data.NewField(name, nil, convertValues(values, func(v json.RawMessage) (bool, error) {
+			return bytes.Equal(v, []byte("true")), nil
+		}))
+	default:
+		return data.NewField(name, nil, convertValues(values, func(v json.RawMessage) (json.RawMessage, error) {
+			return v, nil
+		}))
+	}
+}
+
+// Kinds of JSON values, arrays are reported as objects.
+const (
+	jsonNull = iota
+	jsonNumber
+	jsonString
+	jsonBool
+	jsonObject
+	// jsonInt64 and jsonUint64 are numbers kept as integers, jsonKind reports them as jsonNumber
+	jsonInt64
+	jsonUint64
+)
+
+func jsonKind(v json.RawMessage) int {
+	v = bytes.TrimSpace(v)
+	if len(v) == 0 {
+		return jsonNull
+	}
+	switch v[0] {
+	case 'n':
+		return jsonNull
+	case '"':
+		return jsonString
+	case 't', 'f':
+		return jsonBool
+	case '{', '[':
+		return jsonObject
+	default:
+		return jsonNumber
+	}
+}
+
+// convertValues converts JSON values with convert, missing and null values are nil.
+func convertValues[T any](values []json.RawMessage, convert func(json.RawMessage) (T, error)) []*T {
+	result := make([]*T, len(values))
+	for i, v := range values {
+		if jsonKind(v) == jsonNull {
+			continue
+		}
+		c, err := convert(v)
+		if err != nil {
+			continue
+		}
+		result[i] = &c
+	}
+	return result
+}
@@ -0,0 +1,185 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonValues(values ...string) []json.RawMessage {
+	result := make([]json.RawMessage, len(values))
+	for i, v := range values {
+		result[i] = json.RawMessage(v)
+	}
+	return result
+}
+
+func TestExpandTupleFields(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("time", nil, []int64{1, 2}),
+		data.NewField("req", data.Labels{"host": "a"}, jsonValues(`{"method":"GET","status":200,"cached":true}`, `{"method":"POST","status":null,"cached":false}`)),
+		data.NewField("pair", nil, jsonValues(`["x",1.5]`, `["y",{"k":1}]`)),
+		data.NewField("hits", nil, jsonValues(`[{"host":"a","n":1},{"host":"b","n":2}]`, `[]`)),
+	)
+
+	require.NoError(t, expandTupleFields(frame, func(string) string { return "" }))
+
+	names := make([]string, len(frame.Fields))
+	for i, f := range frame.Fields {
+		names[i] = f.Name
+	}
+	assert.Equal(t, []string{"time", "req.method", "req.status", "req.cached", "pair.1", "pair.2", "hits.host", "hits.n"}, names)
+
+	method, status, cached := frame.Fields[1], frame.Fields[2], frame.Fields[3]
+	assert.Equal(t, data.FieldTypeNullableString, method.Type())
+	assert.Equal(t, ptr("POST"), method.At(1))
+	assert.Equal(t, data.Labels{"host": "a"}, method.Labels)
+	assert.Equal(t, data.FieldTypeNullableFloat64, status.Type())
+	assert.Equal(t, ptr(200.0), status.At(0))
+	assert.Nil(t, status.At(1))
+	assert.Equal(t, data.FieldTypeNullableBool, cached.Type())
+	assert.Equal(t, ptr(false), cached.At(1))
+
+	// elements with values of different kinds stay JSON
+	assert.Equal(t, data.FieldTypeNullableString, frame.Fields[4].Type())
+	assert.Equal(t, data.FieldTypeNullableJSON, frame.Fields[5].Type())
+	assert.Equal(t, ptr(json.RawMessage(`{"k":1}`)), frame.Fields[5].At(1))
+
+	// elements of Nested values are arrays of their values
+	host := frame.Fields[6]
+	assert.Equal(t, data.FieldTypeNullableJSON, host.Type())
+	assert.Equal(t, ptr(json.RawMessage(`["a","b"]`)), host.At(0))
+	assert.Nil(t, host.At(1))
+}
+
+func TestMutateResponseTuples(t *testing.T) {
+	newFrame := func() *data.Frame {
+		return data.NewFrame("A",
+			data.NewField("req", nil, jsonValues(`{"method":"GET"}`)),
+		).SetMeta(&data.FrameMeta{})
+	}
+
+	h := NewHydrolix()
+	frames, err := h.MutateResponse(context.Background(), data.Frames{newFrame()})
+	require.NoError(t, err)
+	assert.Equal(t, "req", frames[0].Fields[0].Name)
+	assert.Equal(t, ptr(`{"method":"GET"}`), frames[0].Fields[0].At(0))
+
+	h.expandTuples = true
+	frames, err = h.MutateResponse(context.Background(), data.Frames{newFrame()})
+	require.NoError(t, err)
+	assert.Equal(t, "req.method", frames[0].Fields[0].Name)
+	assert.Equal(t, ptr("GET"), frames[0].Fields[0].At(0))
+}
+
+func TestExpandTupleFieldsByColumnType(t *testing.T) {
+	types := map[string]string{
+		"req":   "Tuple(method LowCardinality(String), `bytes sent` UInt64, tags Array(String))",
+		"pair":  "Tuple(Enum8('a,b' = 1, 'c' = 2), Nullable(Int64))",
+		"hits":  "Array(Tuple(host String, n UInt8))",
+		"empty": "Tuple(a String, b Int64)",
+	}
+	frame := data.NewFrame("A",
+		data.NewField("req", nil, jsonValues(`{"method":"GET","bytes sent":18446744073709551615,"tags":["a"]}`, `null`)),
+		data.NewField("pair", nil, jsonValues(`["a,b",9007199254740993]`, `["c",null]`)),
+		data.NewField("hits", nil, jsonValues(`[{"host":"a","n":1},{"host":"b","n":2}]`, `[]`)),
+		data.NewField("empty", nil, jsonValues()),
+	)
+
+	require.NoError(t, expandTupleFields(frame, func(name string) string { return types[name] }))
+
+	names := make([]string, len(frame.Fields))
+	for i, f := range frame.Fields {
+		names[i] = f.Name
+	}
+	assert.Equal(t, []string{"req.method", "req.bytes sent", "req.tags", "pair.1", "pair.2", "hits.host", "hits.n",
+		"empty.a", "empty.b"}, names)
+
+	// 64-bit integers keep their precision
+	assert.Equal(t, data.FieldTypeNullableUint64, frame.Fields[1].Type())
+	assert.Equal(t, ptr(uint64(18446744073709551615)), frame.Fields[1].At(0))
+	assert.Nil(t, frame.Fields[1].At(1))
+	assert.Equal(t, data.FieldTypeNullableJSON, frame.Fields[2].Type())
+	assert.Equal(t, data.FieldTypeNullableString, frame.Fields[3].Type())
+	assert.Equal(t, ptr("a,b"), frame.Fields[3].At(0))
+	assert.Equal(t, data.FieldTypeNullableInt64, frame.Fields[4].Type())
+	assert.Equal(t, ptr(int64(9007199254740993)), frame.Fields[4].At(0))
+
+	assert.Equal(t, ptr(json.RawMessage(`["a","b"]`)), frame.Fields[5].At(0))
+	assert.Equal(t, ptr(json.RawMessage(`[]`)), frame.Fields[5].At(1))
+
+	// columns without rows keep their elements
+	assert.Equal(t, data.FieldTypeNullableString, frame.Fields[7].Type())
+	assert.Equal(t, data.FieldTypeNullableInt64, frame.Fields[8].Type())
+	assert.Equal(t, 0, frame.Fields[8].Len())
+}
+
+func TestExpandTupleFieldsWithoutValues(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("empty", nil, jsonValues()),
+		data.NewField("nulls", nil, jsonValues(`null`)),
+	)
+
+	require.NoError(t, expandTupleFields(frame, func(string) string { return "" }))
+
+	// elements are unknown without column type and values, the fields are kept
+	require.Len(t, frame.Fields, 2)
+	assert.Equal(t, "empty", frame.Fields[0].Name)
+	assert.Equal(t, "nulls", frame.Fields[1].Name)
+}
+
+func TestElementFieldIntegers(t *testing.T) {
+	field := elementField("n", jsonValues(`9007199254740993`, `null`, `-1`))
+	assert.Equal(t, data.FieldTypeNullableInt64, field.Type())
+	assert.Equal(t, ptr(int64(9007199254740993)), field.At(0))
+
+	field = elementField("n", jsonValues(`18446744073709551615`, `1`))
+	assert.Equal(t, data.FieldTypeNullableUint64, field.Type())
+
+	// integers in the float64 range and decimals stay float64
+	assert.Equal(t, data.FieldTypeNullableFloat64, elementField("n", jsonValues(`1`, `2`)).Type())
+	assert.Equal(t, data.FieldTypeNullableFloat64, elementField("n", jsonValues(`9007199254740993`, `1.5`)).Type())
+}
+
+func TestTupleElements(t *testing.T) {
+	for chType, want := range map[string][]tupleElement{
+		"Tuple(a String, b Nullable(DateTime64(3, 'UTC')))": {{"a", "String"}, {"b", "Nullable(DateTime64(3, 'UTC'))"}},
+		"Tuple(String, Map(String, UInt8))":                 {{"1", "String"}, {"2", "Map(String, UInt8)"}},
+		"Tuple(`a b` Tuple(c Int8))":                        {{"a b", "Tuple(c Int8)"}},
+	} {
+		elements, nested, ok := tupleElements(chType)
+		require.True(t, ok, chType)
+		assert.False(t, nested, chType)
+		assert.Equal(t, want, elements, chType)
+	}
+
+	elements, nested, ok := tupleElements("Array(Tuple(host String))")
+	require.True(t, ok)
+	assert.True(t, nested)
+	assert.Equal(t, []tupleElement{{"host", "String"}}, elements)
+
+	for _, chType := range []string{"", "String", "Array(String)", "Map(String, UInt8)"} {
+		_, _, ok := tupleElements(chType)
+		assert.False(t, ok, chType)
+	}
+}
+
+func TestMutateResponseTuplesByColumnType(t *testing.T) {
+	h := NewHydrolix()
+	h.expandTuples = true
+	ctx := withColumnTypesCollector(context.Background())
+	columnTypesCollectorFromContext(ctx).types = map[string]string{"req": "Tuple(id Int64)"}
+	frame := data.NewFrame("A",
+		data.NewField("req", nil, jsonValues(`{"id":9007199254740993}`)),
+	).SetMeta(&data.FrameMeta{})
+
+	frames, err := h.MutateResponse(withSafeIntegers(ctx, true), data.Frames{frame})
+	require.NoError(t, err)
+	// expanded integers are converted to strings with safe integers
+	assert.Equal(t, "req.id", frames[0].Fields[0].Name)
+	assert.Equal(t, ptr("9007199254740993"), frames[0].Fields[0].At(0))
+}
@@ -0,0 +1,181 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// Defaults of per-user connection pools of forwardOAuth datasources.
+const (
+	defaultMaxUserPools = 100
+	defaultUserPoolTTL  = time.Hour
+)
+
+// closedPoolError is the error of queries over a closed sql.DB. sqlds caches
+// the pool of every forwarded identity, it reconnects on this error when a
+// query runs over a pool released by userPools.
+const closedPoolError = "sql: database is closed"
+
+// userPoolKey identifies the connection pool of a forwarded identity. The
+// token is hashed so it isn't kept as a map key.
+func userPoolKey(orgID, token string) string {
+	sum := sha256.Sum256([]byte(orgID + "\x00" + token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenExpiry returns the expiration of a JWT identity token, or now+ttl for
+// opaque tokens and tokens without an exp claim. The token is not verified,
+// Hydrolix does it, the expiration only limits how long the pool is kept.
+func tokenExpiry(token string, now time.Time, ttl time.Duration) time.Time {
+	fallback := now.Add(ttl)
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fallback
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return fallback
+	}
+	var claims struct {
+		Exp json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return fallback
+	}
+	exp, err := claims.Exp.Int64()
+	if err != nil {
+		return fallback
+	}
+	return time.Unix(exp, 0)
+}
+
+type userPool struct {
+	db        *sql.DB
+	expiresAt time.Time
+	lastUsed  time.Time
+}
+
+// userPools keeps a connection pool per forwarded identity, so a query never
+// runs over connections opened with another user's token. Pools are released
+// when their token expires or, least recently used first, when the number of
+// pools exceeds the configured maximum. Released pools are closed, sqlds
+// reconnects when a query runs over a pool it cached before the release, see
+// closedPoolError.
+type userPools struct {
+	mu    sync.Mutex
+	pools map[string]*userPool
+	now   func() time.Time // time.Now when nil
+}
+
+func (u *userPools) clock() time.Time {
+	if u.now == nil {
+		return time.Now()
+	}
+	return u.now()
+}
+
+// get returns the pool of the identity if it is still valid.
+func (u *userPools) get(key string) (*sql.DB, bool) {
+	u.mu.Lock()
+	expired := u.removeExpired()
+	p, ok := u.pools[key]
+	if ok {
+		p.lastUsed = u.clock()
+	}
+	u.mu.Unlock()
+
+	closePools(expired)
+	if !ok {
+		return nil, false
+	}
+	return p.db, true
+}
+
+// add registers the pool of the identity, releasing expired pools and the
+// least recently used ones above maxPools. If a concurrent Connect registered
+// a pool of the same identity first, db is closed and the registered pool is
+// returned.
+func (u *userPools) add(key string, db *sql.DB, expiresAt time.Time, maxPools int) *sql.DB {
+	u.mu.Lock()
+	evicted := u.removeExpired()
+	if p, ok := u.pools[key]; ok {
+		p.lastUsed = u.clock()
+		u.mu.Unlock()
+		closePools(evicted)
+		closePools([]*userPool{{db: db}})
+		return p.db
+	}
+	for len(u.pools) >= maxPools {
+		evicted = append(evicted, u.removeLeastRecentlyUsed())
+	}
+	if u.pools == nil {
+		u.pools = map[string]*userPool{}
+	}
+	u.pools[key] = &userPool{db: db, expiresAt: expiresAt, lastUsed: u.clock()}
+	u.mu.Unlock()
+
+	closePools(evicted)
+	return db
+}
+
+func (u *userPools) len() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.pools)
+}
+
+// close releases all pools, when the datasource is disposed.
+func (u *userPools) close() {
+	u.mu.Lock()
+	released := make([]*userPool, 0, len(u.pools))
+	for _, p := range u.pools {
+		released = append(released, p)
+	}
+	u.pools = nil
+	u.mu.Unlock()
+
+	closePools(released)
+}
+
+// removeExpired must be called with mu held.
+func (u *userPools) removeExpired() []*userPool {
+	var expired []*userPool
+	now := u.clock()
+	for key, p := range u.pools {
+		if !now.Before(p.expiresAt) {
+			expired = append(expired, p)
+			delete(u.pools, key)
+		}
+	}
+	return expired
+}
+
+// removeLeastRecentlyUsed must be called with mu held and a non-empty pool map.
+func (u *userPools) removeLeastRecentlyUsed() *userPool {
+	var lruKey string
+	var lru *userPool
+	for key, p := range u.pools {
+		if lru == nil || p.lastUsed.Before(lru.lastUsed) {
+			lruKey, lru = key, p
+		}
+	}
+	delete(u.pools, lruKey)
+	return lru
+}
+
+// closePools closes released pools.
+func closePools(pools []*userPool) {
+	for _, p := range pools {
+		if err := p.db.Close(); err != nil {
+			log.DefaultLogger.Warn("failed to close user connection pool", "err", err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testJWT(claims string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ttl := time.Hour
+
+	assert.Equal(t, time.Unix(1700000600, 0), tokenExpiry(testJWT(`{"sub":"u","exp":1700000600}`), now, ttl))
+	assert.Equal(t, now.Add(ttl), tokenExpiry(testJWT(`{"sub":"u"}`), now, ttl))
+	assert.Equal(t, now.Add(ttl), tokenExpiry("opaque-token", now, ttl))
+	assert.Equal(t, now.Add(ttl), tokenExpiry("a.!!!.c", now, ttl))
+}
+
+func TestUserPoolKey(t *testing.T) {
+	assert.Equal(t, userPoolKey("1", "token"), userPoolKey("1", "token"))
+	assert.NotEqual(t, userPoolKey("1", "token"), userPoolKey("1", "other"))
+	assert.NotEqual(t, userPoolKey("1", "token"), userPoolKey("2", "token"))
+	assert.NotContains(t, userPoolKey("1", "token"), "token")
+}
+
+func openTestDB() *sql.DB {
+	return clickhouse.OpenDB(&clickhouse.Options{Addr: []string{"localhost:9000"}})
+}
+
+func isClosed(db *sql.DB) bool {
+	_, err := db.Conn(context.Background())
+	return err != nil && err.Error() == "sql: database is closed"
+}
+
+func TestUserPools(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	pools := userPools{now: func() time.Time { return now }}
+
+	a, b, c := openTestDB(), openTestDB(), openTestDB()
+	assert.Same(t, a, pools.add("a", a, now.Add(time.Hour), 2))
+	now = now.Add(time.Second)
+	assert.Same(t, b, pools.add("b", b, now.Add(2*time.Minute), 2))
+
+	t.Run("registered pool is returned", func(t *testing.T) {
+		db, ok := pools.get("a")
+		require.True(t, ok)
+		assert.Same(t, a, db)
+		_, ok = pools.get("unknown")
+		assert.False(t, ok)
+	})
+
+	t.Run("concurrently opened pool of the same identity is closed", func(t *testing.T) {
+		dup := openTestDB()
+		assert.Same(t, a, pools.add("a", dup, now.Add(time.Hour), 2))
+		assert.True(t, isClosed(dup))
+	})
+
+	t.Run("least recently used pool is evicted", func(t *testing.T) {
+		now = now.Add(time.Second)
+		_, _ = pools.get("a")
+		assert.Same(t, c, pools.add("c", c, now.Add(time.Hour), 2))
+		assert.True(t, isClosed(b))
+		assert.False(t, isClosed(a))
+		_, ok := pools.get("b")
+		assert.False(t, ok)
+		assert.Equal(t, 2, pools.len())
+	})
+
+	t.Run("expired pools are released", func(t *testing.T) {
+		now = now.Add(2 * time.Hour)
+		_, ok := pools.get("a")
+		assert.False(t, ok)
+		assert.True(t, isClosed(a))
+		assert.True(t, isClosed(c))
+		assert.Equal(t, 0, pools.len())
+	})
+
+	t.Run("pools are released on close", func(t *testing.T) {
+		d, e := openTestDB(), openTestDB()
+		pools.add("d", d, now.Add(time.Hour), 2)
+		pools.add("e", e, now.Add(time.Hour), 2)
+		pools.close()
+		assert.True(t, isClosed(d))
+		assert.True(t, isClosed(e))
+		assert.Equal(t, 0, pools.len())
+	})
+}
+
+func TestConnectForwardOAuthUserPools(t *testing.T) {
+	h := NewHydrolix()
+	config := backend.DataSourceInstanceSettings{
+		JSONData: json.RawMessage(`{"host": "localhost", "port": 8123, "protocol": "http",
+			"credentialsType": "forwardOAuth", "dialTimeout": "10", "maxUserPools": 2}`),
+		DecryptedSecureJSONData: map[string]string{},
+	}
+	args := func(orgID, token string) json.RawMessage {
+		return json.RawMessage(fmt.Sprintf(`{"grafana-http-headers": {"%s": ["Bearer %s"], "%s": ["%s"]}}`,
+			backend.OAuthIdentityTokenHeaderName, token, OrgIdHeaderKey, orgID))
+	}
+	connect := func(orgID, token string) *sql.DB {
+		db, err := h.Connect(context.Background(), config, args(orgID, token))
+		require.NoError(t, err)
+		return db
+	}
+
+	aliceToken := testJWT(fmt.Sprintf(`{"sub":"alice","exp":%d}`, time.Now().Add(time.Hour).Unix()))
+	alice := connect("1", aliceToken)
+	assert.Same(t, alice, connect("1", aliceToken))
+	bob := connect("1", "bob-token")
+	assert.NotSame(t, alice, bob)
+	assert.NotSame(t, bob, connect("2", "bob-token"))
+	assert.Equal(t, 2, h.userPoolCount())
+	// the evicted pool of alice is closed, sqlds reconnects the queries running over it
+	assert.True(t, isClosed(alice))
+	assert.False(t, isClosed(bob))
+	// per-user pools aren't the shared pool of the datasource
+	_, ok := h.pool.get()
+	assert.False(t, ok)
+
+	// the open pools stay within maxUserPools however often tokens change
+	var opened []*sql.DB
+	for i := 0; i < 20; i++ {
+		opened = append(opened, connect("1", fmt.Sprintf("token-%d", i)))
+	}
+	open := 0
+	for _, db := range opened {
+		if !isClosed(db) {
+			open++
+		}
+	}
+	assert.LessOrEqual(t, open, 2)
+	assert.Equal(t, 2, h.userPoolCount())
+
+	// expired token gets a new pool which isn't kept
+	expired := testJWT(fmt.Sprintf(`{"sub":"carol","exp":%d}`, time.Now().Add(-time.Minute).Unix()))
+	assert.NotSame(t, connect("1", expired), connect("1", expired))
+
+	_, err := h.Connect(context.Background(), config, nil)
+	assert.EqualError(t, err, "cannot get auth header")
+}
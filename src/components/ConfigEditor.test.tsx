@@ -3,7 +3,7 @@ import { render, screen, fireEvent } from "@testing-library/react";
 import { ConfigEditor, Props } from "./ConfigEditor";
 import "@testing-library/jest-dom";
 import fs from "fs";
-import { HdxDataSourceOptions } from "types";
+import { HdxDataSourceOptions, Protocol } from "types";
 import allLabels from "labels";
 import defaultConfigs from "defaultConfigs";
 
@@ -89,6 +89,51 @@ describe("ConfigEditor", () => {
     expect(input.checked).toBe(true);
   });
 
+  it("renaming a secure HTTP header requires its value again", () => {
+    const props = getDefaultProps({
+      protocol: Protocol.Http,
+      httpHeaders: [{ name: "X-Key", value: "", secure: true }],
+    });
+    props.options.secureJsonFields = { "httpHeaderValue:X-Key": true };
+    props.onOptionsChange = jest.fn();
+    render(<ConfigEditor {...props} />);
+
+    fireEvent.change(
+      screen.getByLabelText(labels.httpHeaders.namePlaceholder),
+      { target: { value: "X-Other" } }
+    );
+
+    const updated = (props.onOptionsChange as jest.Mock).mock.calls[0][0];
+    expect(updated.secureJsonFields).toEqual({
+      "httpHeaderValue:X-Key": false,
+      "httpHeaderValue:X-Other": false,
+    });
+    expect(updated.secureJsonData).toMatchObject({
+      "httpHeaderValue:X-Key": "",
+      "httpHeaderValue:X-Other": "",
+    });
+  });
+
+  it("secure HTTP header values are keyed by the trimmed name", () => {
+    const props = getDefaultProps({
+      protocol: Protocol.Http,
+      httpHeaders: [{ name: "X-Key", value: "", secure: true }],
+    });
+    props.options.secureJsonFields = { "httpHeaderValue:X-Key": true };
+    props.onOptionsChange = jest.fn();
+    render(<ConfigEditor {...props} />);
+
+    fireEvent.change(
+      screen.getByLabelText(labels.httpHeaders.namePlaceholder),
+      { target: { value: "X-Key " } }
+    );
+
+    const updated = (props.onOptionsChange as jest.Mock).mock.calls[0][0];
+    expect(updated.secureJsonFields).toEqual({
+      "httpHeaderValue:X-Key": true,
+    });
+  });
+
   // it('port input is enabled', () => {
   //     let component = render(<ConfigEditor {...getDefaultProps({})} />);
   //     expect(component.container.querySelector('#config-editor-port')?.getAttribute("disabled")).toBeNull();
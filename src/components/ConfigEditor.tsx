@@ -16,18 +16,27 @@ import {
   Input,
   RadioButtonGroup,
   SecretInput,
+  SecretTextArea,
   Select,
   Stack,
   Switch,
+  TagsInput,
   TextArea,
   TimeRangeInput,
 } from "@grafana/ui";
 import { ConfigSection } from "@grafana/plugin-ui";
 import {
+  Compression,
+  ConnectionStrategy,
   CredentialsType,
+  GeoPointFormat,
   HdxDataSourceOptions,
   HdxSecureJsonData,
+  HttpHeader,
   Protocol,
+  TypeMapping,
+  TypeMappingFieldType,
+  TypeMappingMode,
 } from "../types";
 import allLabels from "labels";
 import defaultConfigs from "defaultConfigs";
@@ -78,6 +87,32 @@ export function ConfigEditor(props: Props) {
     { label: "Native", value: Protocol.Native },
     { label: "HTTP", value: Protocol.Http },
   ];
+  const nativeCompressionOptions = [
+    { label: "None", value: Compression.None },
+    { label: "LZ4", value: Compression.LZ4 },
+    { label: "ZSTD", value: Compression.ZSTD },
+  ];
+  const httpCompressionOptions = [
+    ...nativeCompressionOptions,
+    { label: "gzip", value: Compression.GZIP },
+    { label: "deflate", value: Compression.Deflate },
+    { label: "br", value: Compression.Brotli },
+  ];
+  const compressionOptions =
+    jsonData.protocol === Protocol.Http
+      ? httpCompressionOptions
+      : nativeCompressionOptions;
+  const defaultCompression =
+    jsonData.protocol === Protocol.Http ? Compression.None : Compression.LZ4;
+  const connectionStrategyOptions = [
+    { label: "In order", value: ConnectionStrategy.InOrder },
+    { label: "Round robin", value: ConnectionStrategy.RoundRobin },
+    { label: "Random", value: ConnectionStrategy.Random },
+  ];
+  const geoPointFormatOptions = [
+    { label: "Latitude and longitude", value: GeoPointFormat.LatLon },
+    { label: "GeoJSON", value: GeoPointFormat.GeoJSON },
+  ];
   const credentialsTypesOptions = [
     { label: "User Account", value: CredentialsType.UserAccount },
     { label: "Service Account", value: CredentialsType.ServiceAccount },
@@ -187,6 +222,11 @@ export function ConfigEditor(props: Props) {
         port: jsonData.useDefaultPort
           ? +getDefaultPort(protocol, jsonData.secure!)
           : jsonData.port,
+        // HTTP level compression is not available for the native protocol
+        ...(protocol === Protocol.Native &&
+          !nativeCompressionOptions.some(
+            (o) => o.value === jsonData.compression
+          ) && { compression: undefined, compressionLevel: undefined }),
       },
     });
   };
@@ -235,6 +275,104 @@ export function ConfigEditor(props: Props) {
       },
     });
   };
+  const httpHeaders = jsonData.httpHeaders ?? [];
+  // Names are trimmed like the backend does when it reads the values
+  const httpHeaderValueKey = (name: string) =>
+    `httpHeaderValue:${name.trim()}` as const;
+  // Secure header values are keyed by the header name, so removing a secure
+  // header clears its stored value. Stored values can't be read back, renaming
+  // a secure header clears its value and requires it to be entered again.
+  const onHttpHeadersChange = (
+    headers: HttpHeader[],
+    secureChanges: Record<string, string> = {}
+  ) => {
+    const resetFields = Object.keys(secureChanges).reduce(
+      (acc, key) => ({ ...acc, [key]: false }),
+      {}
+    );
+    onOptionsChange({
+      ...options,
+      jsonData: { ...jsonData, httpHeaders: headers },
+      secureJsonFields: { ...options.secureJsonFields, ...resetFields },
+      secureJsonData: { ...options.secureJsonData, ...secureChanges },
+    });
+  };
+  const onHttpHeaderUpdate = (index: number, update: Partial<HttpHeader>) => {
+    const header = httpHeaders[index];
+    const updated = { ...header, ...update };
+    const headers = httpHeaders.map((h, i) => (i === index ? updated : h));
+    const secureChanges: Record<string, string> = {};
+    const key = httpHeaderValueKey(header.name);
+    const updatedKey = httpHeaderValueKey(updated.name);
+    if (header.secure && (!updated.secure || updatedKey !== key)) {
+      secureChanges[key] = "";
+    }
+    if (header.secure && updated.secure && updatedKey !== key) {
+      secureChanges[updatedKey] = "";
+    }
+    if (updated.secure && !header.secure) {
+      secureChanges[httpHeaderValueKey(updated.name)] = header.value ?? "";
+      updated.value = "";
+    }
+    onHttpHeadersChange(headers, secureChanges);
+  };
+  const onHttpHeaderRemove = (index: number) => {
+    const header = httpHeaders[index];
+    onHttpHeadersChange(
+      httpHeaders.filter((_, i) => i !== index),
+      header.secure ? { [httpHeaderValueKey(header.name)]: "" } : {}
+    );
+  };
+  const onHttpHeaderSecureValueChange = (name: string, value: string) => {
+    onOptionsChange({
+      ...options,
+      secureJsonData: {
+        ...options.secureJsonData,
+        [httpHeaderValueKey(name)]: value,
+      },
+    });
+  };
+
+  const typeMappings = jsonData.typeMappings ?? [];
+  const typeMappingFieldTypeOptions = [
+    { label: "String", value: TypeMappingFieldType.String },
+    { label: "Number", value: TypeMappingFieldType.Number },
+    { label: "Boolean", value: TypeMappingFieldType.Boolean },
+    { label: "JSON", value: TypeMappingFieldType.JSON },
+  ];
+  const typeMappingModeOptions = [
+    { label: "Default", value: TypeMappingMode.Default },
+    { label: "Labels", value: TypeMappingMode.Labels },
+  ];
+  const onTypeMappingsChange = (mappings: TypeMapping[]) => {
+    onOptionsChange({
+      ...options,
+      jsonData: { ...jsonData, typeMappings: mappings },
+    });
+  };
+  const onTypeMappingUpdate = (
+    index: number,
+    update: Partial<TypeMapping>
+  ) => {
+    const mappings = typeMappings.map((m, i) =>
+      i === index ? { ...m, ...update } : m
+    );
+    onTypeMappingsChange(mappings);
+  };
+
+  const onResetSecureField = (key: keyof HdxSecureJsonData) => () => {
+    onOptionsChange({
+      ...options,
+      secureJsonFields: {
+        ...options.secureJsonFields,
+        [key]: false,
+      },
+      secureJsonData: {
+        ...options.secureJsonData,
+        [key]: "",
+      },
+    });
+  };
   const onResetPassword = () => {
     onOptionsChange({
       ...options,
@@ -433,6 +571,44 @@ export function ConfigEditor(props: Props) {
             </Stack>
           </Field>
 
+          <Field
+            data-testid={labels.hosts.testId}
+            label={labels.hosts.label}
+            description={labels.hosts.description}
+          >
+            <TagsInput
+              width={80}
+              tags={jsonData.hosts ?? []}
+              placeholder={labels.hosts.placeholder}
+              onChange={(hosts) =>
+                onOptionsChange({
+                  ...options,
+                  jsonData: { ...jsonData, hosts },
+                })
+              }
+            />
+          </Field>
+          {(jsonData.hosts?.length ?? 0) > 0 && (
+            <Field
+              data-testid={labels.connectionStrategy.testId}
+              label={labels.connectionStrategy.label}
+              description={labels.connectionStrategy.description}
+            >
+              <RadioButtonGroup<ConnectionStrategy>
+                options={connectionStrategyOptions}
+                value={
+                  jsonData.connectionStrategy ?? ConnectionStrategy.InOrder
+                }
+                onChange={(connectionStrategy) =>
+                  onOptionsChange({
+                    ...options,
+                    jsonData: { ...jsonData, connectionStrategy },
+                  })
+                }
+              />
+            </Field>
+          )}
+
           <Field
             data-testid={labels.protocol.testId}
             label={labels.protocol.label}
@@ -445,6 +621,58 @@ export function ConfigEditor(props: Props) {
               onChange={(e) => onProtocolToggle(e!)}
             />
           </Field>
+          <Field
+            data-testid={labels.compression.testId}
+            label={labels.compression.label}
+            description={labels.compression.description}
+          >
+            <RadioButtonGroup<Compression>
+              options={compressionOptions}
+              value={jsonData.compression ?? defaultCompression}
+              onChange={(compression) =>
+                onOptionsChange({
+                  ...options,
+                  jsonData: {
+                    ...jsonData,
+                    compression,
+                    compressionLevel: undefined,
+                  },
+                })
+              }
+            />
+          </Field>
+          {jsonData.compression &&
+            ![Compression.None, Compression.LZ4].includes(
+              jsonData.compression
+            ) && (
+              <Field
+                data-testid={labels.compressionLevel.testId}
+                label={labels.compressionLevel.label}
+                description={labels.compressionLevel.description}
+              >
+                <Input
+                  name="compressionLevel"
+                  width={40}
+                  type="number"
+                  min={1}
+                  max={jsonData.compression === Compression.ZSTD ? 22 : 9}
+                  value={jsonData.compressionLevel || ""}
+                  onChange={(e) =>
+                    onOptionsChange({
+                      ...options,
+                      jsonData: {
+                        ...jsonData,
+                        compressionLevel: e.currentTarget.value
+                          ? +e.currentTarget.value
+                          : undefined,
+                      },
+                    })
+                  }
+                  aria-label={labels.compressionLevel.label}
+                  placeholder={labels.compressionLevel.placeholder}
+                />
+              </Field>
+            )}
           {jsonData.protocol === Protocol.Http &&
             !jsonData.secure &&
             secureJsonFields.password && (
@@ -482,6 +710,28 @@ export function ConfigEditor(props: Props) {
               />
             </Field>
           )}
+          {jsonData.protocol === Protocol.Http && (
+            <Field
+              data-testid={labels.streamingResult.testId}
+              label={labels.streamingResult.label}
+              description={labels.streamingResult.description}
+            >
+              <Switch
+                id="streamingResult"
+                className="gf-form"
+                value={jsonData.streamingResult ?? false}
+                onChange={(e) =>
+                  onOptionsChange({
+                    ...options,
+                    jsonData: {
+                      ...jsonData,
+                      streamingResult: e.currentTarget.checked,
+                    },
+                  })
+                }
+              />
+            </Field>
+          )}
         </ConfigSection>
 
         {jsonData.secure && (
@@ -499,11 +749,238 @@ export function ConfigEditor(props: Props) {
                   onChange={(e) => onTlsSettingsChange(e.currentTarget.checked)}
                 />
               </Field>
+              <Field
+                data-testid={labels.tlsServerName.testId}
+                label={labels.tlsServerName.label}
+                description={labels.tlsServerName.description}
+              >
+                <Input
+                  name="tlsServerName"
+                  width={80}
+                  value={jsonData.tlsServerName || ""}
+                  onChange={onUpdateDatasourceJsonDataOption(
+                    props,
+                    "tlsServerName"
+                  )}
+                  aria-label={labels.tlsServerName.label}
+                  placeholder={labels.tlsServerName.placeholder}
+                />
+              </Field>
+              {(["tlsCACert", "tlsClientCert", "tlsClientKey"] as const).map(
+                (key) => (
+                  <Field
+                    key={key}
+                    data-testid={labels[key].testId}
+                    label={labels[key].label}
+                    description={labels[key].description}
+                  >
+                    <SecretTextArea
+                      cols={80}
+                      rows={6}
+                      aria-label={labels[key].label}
+                      placeholder={labels[key].placeholder}
+                      value={secureJsonData[key] || ""}
+                      isConfigured={
+                        (secureJsonFields && secureJsonFields[key]) as boolean
+                      }
+                      onReset={onResetSecureField(key)}
+                      onChange={onUpdateDatasourceSecureJsonDataOption(
+                        props,
+                        key
+                      )}
+                    />
+                  </Field>
+                )
+              )}
             </ConfigSection>
           </>
         )}
 
         <Divider />
+        <ConfigSection
+          title="Proxy"
+          isCollapsible
+          isInitiallyOpen={!!jsonData.proxyUrl}
+        >
+          <Field
+            data-testid={labels.proxyUrl.testId}
+            label={labels.proxyUrl.label}
+            description={labels.proxyUrl.description}
+          >
+            <Input
+              name="proxyUrl"
+              width={80}
+              value={jsonData.proxyUrl || ""}
+              onChange={onUpdateDatasourceJsonDataOption(props, "proxyUrl")}
+              aria-label={labels.proxyUrl.label}
+              placeholder={labels.proxyUrl.placeholder}
+            />
+          </Field>
+          {jsonData.proxyUrl && (
+            <>
+              <Field
+                data-testid={labels.proxyUsername.testId}
+                label={labels.proxyUsername.label}
+                description={labels.proxyUsername.description}
+              >
+                <Input
+                  name="proxyUsername"
+                  width={40}
+                  value={jsonData.proxyUsername || ""}
+                  onChange={onUpdateDatasourceJsonDataOption(
+                    props,
+                    "proxyUsername"
+                  )}
+                  aria-label={labels.proxyUsername.label}
+                  placeholder={labels.proxyUsername.placeholder}
+                />
+              </Field>
+              <Field
+                data-testid={labels.proxyPassword.testId}
+                label={labels.proxyPassword.label}
+                description={labels.proxyPassword.description}
+              >
+                <SecretInput
+                  name="proxyPassword"
+                  width={40}
+                  aria-label={labels.proxyPassword.label}
+                  placeholder={labels.proxyPassword.placeholder}
+                  value={secureJsonData.proxyPassword || ""}
+                  isConfigured={
+                    (secureJsonFields &&
+                      secureJsonFields.proxyPassword) as boolean
+                  }
+                  onReset={onResetSecureField("proxyPassword")}
+                  onChange={onUpdateDatasourceSecureJsonDataOption(
+                    props,
+                    "proxyPassword"
+                  )}
+                />
+              </Field>
+              <Field
+                data-testid={labels.noProxy.testId}
+                label={labels.noProxy.label}
+                description={labels.noProxy.description}
+              >
+                <TagsInput
+                  width={80}
+                  tags={jsonData.noProxy ?? []}
+                  placeholder={labels.noProxy.placeholder}
+                  onChange={(noProxy) =>
+                    onOptionsChange({
+                      ...options,
+                      jsonData: { ...jsonData, noProxy },
+                    })
+                  }
+                />
+              </Field>
+            </>
+          )}
+        </ConfigSection>
+
+        <Divider />
+        {jsonData.protocol === Protocol.Http && (
+          <>
+            <ConfigSection
+              title="HTTP Headers"
+              isCollapsible
+              isInitiallyOpen={httpHeaders.length > 0}
+            >
+              <Field
+                data-testid={labels.httpHeaders.testId}
+                label={labels.httpHeaders.label}
+                description={labels.httpHeaders.description}
+              >
+                <Stack direction={"column"}>
+                  {httpHeaders.map((header, index) => {
+                    const valueKey = httpHeaderValueKey(header.name);
+                    return (
+                      <Stack direction={"row"} key={index}>
+                        <Input
+                          width={30}
+                          value={header.name}
+                          placeholder={labels.httpHeaders.namePlaceholder}
+                          aria-label={labels.httpHeaders.namePlaceholder}
+                          onChange={(e) =>
+                            onHttpHeaderUpdate(index, {
+                              name: e.currentTarget.value,
+                            })
+                          }
+                        />
+                        {header.secure ? (
+                          <SecretInput
+                            width={40}
+                            value={secureJsonData[valueKey] || ""}
+                            placeholder={labels.httpHeaders.valuePlaceholder}
+                            aria-label={labels.httpHeaders.valuePlaceholder}
+                            isConfigured={
+                              !!(
+                                secureJsonFields && secureJsonFields[valueKey]
+                              )
+                            }
+                            onReset={onResetSecureField(valueKey)}
+                            onChange={(e) =>
+                              onHttpHeaderSecureValueChange(
+                                header.name,
+                                e.currentTarget.value
+                              )
+                            }
+                          />
+                        ) : (
+                          <Input
+                            width={40}
+                            value={header.value || ""}
+                            placeholder={labels.httpHeaders.valuePlaceholder}
+                            aria-label={labels.httpHeaders.valuePlaceholder}
+                            onChange={(e) =>
+                              onHttpHeaderUpdate(index, {
+                                value: e.currentTarget.value,
+                              })
+                            }
+                          />
+                        )}
+                        <InlineSwitch
+                          label={labels.httpHeaders.secureLabel}
+                          showLabel
+                          value={!!header.secure}
+                          onChange={(e) =>
+                            onHttpHeaderUpdate(index, {
+                              secure: e.currentTarget.checked,
+                            })
+                          }
+                        />
+                        <Button
+                          aria-label={""}
+                          variant="destructive"
+                          icon="times"
+                          size={"sm"}
+                          style={{ marginTop: "4.5px" }}
+                          onClick={() => onHttpHeaderRemove(index)}
+                        />
+                      </Stack>
+                    );
+                  })}
+                  <div>
+                    <Button
+                      variant="secondary"
+                      icon="plus"
+                      size={"sm"}
+                      onClick={() =>
+                        onHttpHeadersChange([
+                          ...httpHeaders,
+                          { name: "", value: "" },
+                        ])
+                      }
+                    >
+                      {labels.httpHeaders.addLabel}
+                    </Button>
+                  </div>
+                </Stack>
+              </Field>
+            </ConfigSection>
+            <Divider />
+          </>
+        )}
 
         <ConfigSection title="Credentials">
           <Field
@@ -586,6 +1063,36 @@ export function ConfigEditor(props: Props) {
               </Field>
             </>
           )}
+          {jsonData.credentialsType === CredentialsType.ForwardOAuth &&
+            (["maxUserPools", "userPoolTTL"] as const).map((key) => (
+              <Field
+                key={key}
+                data-testid={labels[key].testId}
+                label={labels[key].label}
+                description={labels[key].description}
+              >
+                <Input
+                  name={key}
+                  width={40}
+                  type="number"
+                  min={0}
+                  value={jsonData[key] || ""}
+                  onChange={(e) =>
+                    onOptionsChange({
+                      ...options,
+                      jsonData: {
+                        ...jsonData,
+                        [key]: e.currentTarget.value
+                          ? +e.currentTarget.value
+                          : undefined,
+                      },
+                    })
+                  }
+                  aria-label={labels[key].label}
+                  placeholder={labels[key].placeholder}
+                />
+              </Field>
+            ))}
         </ConfigSection>
         <Divider />
         <ConfigSection
@@ -704,6 +1211,201 @@ export function ConfigEditor(props: Props) {
               type="number"
             />
           </Field>
+          <Field
+            data-testid={labels.schemaCacheTTL.testId}
+            label={labels.schemaCacheTTL.label}
+            description={labels.schemaCacheTTL.description}
+          >
+            <Input
+              name={"schemaCacheTTL"}
+              width={40}
+              type="number"
+              min={-1}
+              value={jsonData.schemaCacheTTL || ""}
+              onChange={(e) =>
+                onOptionsChange({
+                  ...options,
+                  jsonData: {
+                    ...jsonData,
+                    schemaCacheTTL: e.currentTarget.value
+                      ? +e.currentTarget.value
+                      : undefined,
+                  },
+                })
+              }
+              aria-label={labels.schemaCacheTTL.label}
+              placeholder={labels.schemaCacheTTL.placeholder}
+            />
+          </Field>
+          <Divider />
+          <ConfigSection title="Connection Pool">
+            {(
+              [
+                "maxOpenConns",
+                "maxIdleConns",
+                "connMaxIdleTime",
+                "connMaxLifetime",
+              ] as const
+            ).map((key) => (
+              <Field
+                key={key}
+                data-testid={labels[key].testId}
+                label={labels[key].label}
+                description={labels[key].description}
+              >
+                <Input
+                  name={key}
+                  width={40}
+                  value={jsonData[key] || ""}
+                  onChange={(e) => {
+                    onOptionsChange({
+                      ...options,
+                      jsonData: {
+                        ...jsonData,
+                        [key]: e.currentTarget.value
+                          ? +e.currentTarget.value
+                          : undefined,
+                      },
+                    });
+                  }}
+                  label={labels[key].label}
+                  aria-label={labels[key].label}
+                  placeholder={labels[key].placeholder}
+                  type="number"
+                  min={0}
+                />
+              </Field>
+            ))}
+          </ConfigSection>
+          <Divider />
+          <ConfigSection title="Data Types">
+            {(
+              [
+                "decimalAsString",
+                "wideIntegersAsFloat",
+                "widenFloat32",
+                "expandTuples",
+                "flattenJSON",
+                "safeIntegers",
+              ] as const
+            ).map((key) => (
+              <Field
+                key={key}
+                data-testid={labels[key].testId}
+                label={labels[key].label}
+                description={labels[key].description}
+              >
+                <Switch
+                  id={key}
+                  className="gf-form"
+                  value={jsonData[key] ?? false}
+                  onChange={(e) => {
+                    onOptionsChange({
+                      ...options,
+                      jsonData: {
+                        ...jsonData,
+                        [key]: e.currentTarget.checked,
+                      },
+                    });
+                  }}
+                />
+              </Field>
+            ))}
+            <Field
+              data-testid={labels.geoPointFormat.testId}
+              label={labels.geoPointFormat.label}
+              description={labels.geoPointFormat.description}
+            >
+              <RadioButtonGroup<GeoPointFormat>
+                options={geoPointFormatOptions}
+                value={jsonData.geoPointFormat ?? GeoPointFormat.LatLon}
+                onChange={(geoPointFormat) =>
+                  onOptionsChange({
+                    ...options,
+                    jsonData: { ...jsonData, geoPointFormat },
+                  })
+                }
+              />
+            </Field>
+            <Field
+              data-testid={labels.typeMappings.testId}
+              label={labels.typeMappings.label}
+              description={labels.typeMappings.description}
+            >
+              <Stack direction={"column"}>
+                {typeMappings.map((mapping, index) => (
+                  <Stack direction={"row"} key={index}>
+                    <Input
+                      width={30}
+                      value={mapping.typeRegex}
+                      placeholder={labels.typeMappings.regexPlaceholder}
+                      aria-label={labels.typeMappings.regexPlaceholder}
+                      onChange={(e) =>
+                        onTypeMappingUpdate(index, {
+                          typeRegex: e.currentTarget.value,
+                        })
+                      }
+                    />
+                    <Select<TypeMappingFieldType>
+                      width={20}
+                      options={typeMappingFieldTypeOptions}
+                      value={mapping.fieldType}
+                      onChange={(v) =>
+                        onTypeMappingUpdate(index, {
+                          fieldType: v.value!,
+                          mode:
+                            v.value === TypeMappingFieldType.String
+                              ? mapping.mode
+                              : TypeMappingMode.Default,
+                        })
+                      }
+                    />
+                    <Select<TypeMappingMode>
+                      width={20}
+                      options={typeMappingModeOptions}
+                      value={mapping.mode ?? TypeMappingMode.Default}
+                      disabled={
+                        mapping.fieldType !== TypeMappingFieldType.String
+                      }
+                      onChange={(v) =>
+                        onTypeMappingUpdate(index, { mode: v.value })
+                      }
+                    />
+                    <Button
+                      aria-label={""}
+                      variant="destructive"
+                      icon="times"
+                      size={"sm"}
+                      style={{ marginTop: "4.5px" }}
+                      onClick={() =>
+                        onTypeMappingsChange(
+                          typeMappings.filter((_, i) => i !== index)
+                        )
+                      }
+                    />
+                  </Stack>
+                ))}
+                <div>
+                  <Button
+                    variant="secondary"
+                    icon="plus"
+                    size={"sm"}
+                    onClick={() =>
+                      onTypeMappingsChange([
+                        ...typeMappings,
+                        {
+                          typeRegex: "",
+                          fieldType: TypeMappingFieldType.String,
+                        },
+                      ])
+                    }
+                  >
+                    {labels.typeMappings.addLabel}
+                  </Button>
+                </div>
+              </Stack>
+            </Field>
+          </ConfigSection>
           <Divider />
           <ConfigSection title="Error Exposure">
             <Field
@@ -28,6 +28,7 @@ import {
   ToolbarButton,
 } from "@grafana/ui";
 import { QUERY_DURATION_REGEX } from "../editor/timeRangeUtils";
+import { setDiagnostics } from "../editor/queryValidation";
 import { InterpolatedQuery } from "./InterpolatedQuery";
 import { ValidationBar } from "./ValidationBar";
 import { useDebounce } from "react-use";
@@ -83,6 +84,11 @@ export function QueryEditor(props: Props) {
     },
     [queryTypeOptions]
   );
+  const safeIntegersOptions: Array<SelectableValue<boolean | undefined>> = [
+    { label: "Default", value: undefined },
+    { label: "On", value: true },
+    { label: "Off", value: false },
+  ];
 
   const [queryType, setQueryType] = useState(
     getQueryTypeValue(props.query.format ?? QueryType.Table)
@@ -126,6 +132,16 @@ export function QueryEditor(props: Props) {
   const onQueryTextChange = (queryText: string) => {
     props.onChange({ ...props.query, rawSql: queryText });
   };
+  // queries are formatted by the format resource, which keeps comments and
+  // macros as written, queries it can't parse by the editor formatter
+  const onFormat = async (formatInEditor: () => void) => {
+    const response = await props.datasource.formatQuery(props.query.rawSql);
+    if (response.error) {
+      formatInEditor();
+    } else {
+      onQueryTextChange(response.data);
+    }
+  };
   const onSettingsChange = (settings: QuerySetting[]) => {
     props.onChange({ ...props.query, querySettings: settings });
   };
@@ -168,6 +184,21 @@ export function QueryEditor(props: Props) {
     300,
     [showSql, interpolationId]
   );
+  // validation markers and their quick fixes
+  useDebounce(
+    async () => {
+      if (!monaco) {
+        return;
+      }
+      const query = props.query.rawSql ?? "";
+      const diagnostics = query
+        ? (await props.datasource.validateQuery(query)).data
+        : [];
+      setDiagnostics(monaco, query, diagnostics);
+    },
+    500,
+    [monaco, props.query.rawSql]
+  );
   // eslint-disable-next-line eqeqeq
   let dirty = interpolationResult?.interpolationId != interpolationId;
   return (
@@ -227,6 +258,28 @@ export function QueryEditor(props: Props) {
                     value={props.query.round}
                   />
                 </InlineField>
+                <InlineField
+                  label={
+                    <InlineLabel
+                      width={15}
+                      tooltip={labels.safeIntegers.tooltip}
+                    >
+                      {labels.safeIntegers.label}
+                    </InlineLabel>
+                  }
+                >
+                  <Select
+                    data-testid="data-testid safe integers"
+                    options={safeIntegersOptions}
+                    value={safeIntegersOptions.find(
+                      (o) => o.value === props.query.safeIntegers
+                    )}
+                    onChange={(v) =>
+                      props.onChange({ ...props.query, safeIntegers: v.value })
+                    }
+                    size={"sm"}
+                  />
+                </InlineField>
                 {showSql ? (
                   <Button
                     variant={"secondary"}
@@ -248,7 +301,7 @@ export function QueryEditor(props: Props) {
                   <ToolbarButton
                     style={{ display: "table-cell" }}
                     tooltip={labels.formatQuery.tooltip}
-                    onClick={formatQuery}
+                    onClick={() => onFormat(formatQuery)}
                   >
                     <Icon name="brackets-curly" />
                   </ToolbarButton>
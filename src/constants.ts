@@ -4,12 +4,6 @@ export const SHOW_INTERPOLATED_QUERY_ERRORS = false;
 export const SYNTHETIC_NULL = "__null__";
 export const SYNTHETIC_EMPTY = "__empty__";
 
-export const SCHEMA_SQL =
-  "SELECT DISTINCT database as project FROM system.tables WHERE engine = 'TurbineStorage' AND (project != 'sample_project' AND project != 'hdx' AND total_rows > 0)";
-export const TABLES_SQL =
-  "SELECT name FROM system.tables WHERE engine = 'TurbineStorage' AND database = '{schema}' AND total_rows > 0";
-export const COLUMNS_SQL =
-  "SELECT name FROM system.columns WHERE database='{schema}' AND table ='{table}'";
 export const PK_SQL =
   "SELECT primary_key FROM system.tables WHERE database='{schema}' AND table ='{table}'";
 export const FUNCTIONS_SQL = "SELECT name FROM  system.functions";
@@ -52,7 +46,8 @@ export const MAP_TYPES = [...SUPPORTED_TYPES, ...NULLABLE_TYPES].map(
   (t) => `Map(String, ${t})`
 );
 
-export const VARIABLE_REGEX = /(?<=\$\{)\w+(?=})|(?<=\$)\w+/;
+// $var or ${var:format}, the name is the first or the second group
+export const VARIABLE_REGEX = /^\$(?:(\w+)|\{(\w+)(?::\w+)?})$/;
 
 export const MAP_KEY_REGEX = /^.*\['.*']$/;
 
@@ -32,6 +32,16 @@ import {
   TableIdentifier,
   InterpolationResponse,
   QuerySetting,
+  ValidationResponse,
+  FormatOptions,
+  FormatResponse,
+  ResourceResponse,
+  ResourceErrorCode,
+  MacroCTE,
+  Diagnostic,
+  SchemaListResponse,
+  ColumnsResponse,
+  SchemaColumn,
 } from "./types";
 import { from, Observable, switchMap } from "rxjs";
 import { map } from "rxjs/operators";
@@ -252,7 +262,7 @@ export class DataSource extends DataSourceWithBackend<
   }
 
   wrapSyntaxError(errorMessage: string, query: string) {
-    if (!errorMessage || errorMessage === "Unknown Error") {
+    if (!errorMessage || errorMessage.startsWith("internal error")) {
       return `Cannot apply ad hoc filter: unknown error occurred while parsing query '${query}'`;
     }
     const fullMessage = errorMessage;
@@ -338,20 +348,17 @@ export class DataSource extends DataSourceWithBackend<
   }
 
   async getInterpolatedQuery(query: HdxQuery): Promise<InterpolationResponse> {
-    return this.postResource("interpolate", {
-      data: {
+    return this.resourceRequest<string>(
+      "interpolate",
+      {
         rawSql: query.rawSql,
         range: this.options?.range,
         interval: this.options?.interval,
         filters: this.filters,
         round: query.round,
       },
-    }).then((a: any) => ({
-      error: a.error,
-      errorMessage: a.errorMessage,
-      data: a.data as string,
-      originalSql: query.rawSql,
-    }));
+      query.rawSql
+    );
   }
 
   async getMacroCTE(query: string): Promise<MacroCTEResponse> {
@@ -363,14 +370,76 @@ export class DataSource extends DataSourceWithBackend<
         originalSql: query,
       };
     }
-    return this.postResource("macroCTE", {
-      data: { query },
-    }).then((a: any) => ({
-      error: a.error,
-      errorMessage: a.errorMessage,
-      data: a.data,
-      originalSql: query,
-    }));
+    return this.resourceRequest<MacroCTE[]>("macroCTE", { query }, query);
+  }
+
+  async validateQuery(query: string): Promise<ValidationResponse> {
+    const response = await this.resourceRequest<Diagnostic[]>(
+      "validate",
+      { query },
+      query
+    );
+    return { ...response, data: response.data ?? [] };
+  }
+
+  async formatQuery(
+    query: string,
+    options?: FormatOptions
+  ): Promise<FormatResponse> {
+    return this.resourceRequest<string>("format", { query, ...options }, query);
+  }
+
+  /** Lists the databases of Hydrolix tables, cached by the backend */
+  async getDatabases(): Promise<SchemaListResponse> {
+    return this.resourceRequest<string[]>("schema/databases", {}, "");
+  }
+
+  async getTables(database: string): Promise<SchemaListResponse> {
+    return this.resourceRequest<string[]>("schema/tables", { database }, "");
+  }
+
+  async getColumns(database: string, table: string): Promise<ColumnsResponse> {
+    return this.resourceRequest<SchemaColumn[]>(
+      "schema/columns",
+      { database, table },
+      ""
+    );
+  }
+
+  /**
+   * Posts data to a resource route. Failed requests respond with a 4xx or 5xx
+   * status and an error code telling bad requests and queries apart from
+   * database and plugin failures, they resolve to an error response rather
+   * than rejecting.
+   */
+  private async resourceRequest<T>(
+    path: string,
+    data: any,
+    originalSql: string
+  ): Promise<ResourceResponse<T>> {
+    try {
+      const a: any = await this.postResource(
+        path,
+        { data },
+        { showErrorAlert: false }
+      );
+      return {
+        error: a.error,
+        errorMessage: a.errorMessage,
+        data: a.data as T,
+        originalSql,
+      };
+    } catch (e: any) {
+      const body = e?.data ?? {};
+      return {
+        error: true,
+        errorMessage: body.errorMessage ?? e?.message ?? String(e),
+        errorCode: body.errorCode ?? ResourceErrorCode.Internal,
+        position: body.position,
+        data: undefined as T,
+        originalSql,
+      };
+    }
   }
 
   async getTagValues(
@@ -12,6 +12,7 @@ import { OPERATORS } from "./operators";
 import { FUNCTIONS } from "./functions";
 import { Props } from "../components/QueryEditor";
 import { applyHotKey, updateOptions } from "./editorUtils";
+import { registerQuickFixes } from "./queryValidation";
 import { v4 as uuidv4 } from "uuid";
 
 export const languageDefinition: (
@@ -46,6 +47,7 @@ export const languageDefinition: (
       };
       updateOptions(m);
       setKeywords(m, language);
+      registerQuickFixes(m);
       applyHotKey(m, props);
       setMonaco(m);
 
@@ -0,0 +1,37 @@
+import fs from "fs";
+import { MACROS } from "./macros";
+import { applyConditionalAll, emptyContext } from "../macros/macrosApplier";
+
+interface MacroFixture {
+  name: string;
+  macro: string;
+  query: string;
+  expected: string;
+}
+
+// Cases of the backend macros, run by pkg/macros/macros_test.go as well
+const fixtures: { cases: MacroFixture[] } = JSON.parse(
+  fs.readFileSync("./pkg/macros/testdata/macros.json", "utf-8")
+);
+
+describe("MACROS", () => {
+  it("offers the macros implemented by the backend", () => {
+    const backend = new Set(fixtures.cases.map((c) => `$__${c.macro}`));
+    expect(MACROS.map((m) => m.text).sort()).toEqual([...backend].sort());
+  });
+
+  test.each(fixtures.cases)("$name uses $macro", ({ macro, query }) => {
+    const macroRegex = new RegExp(`\\$__${macro}\\b`);
+    expect(query).toMatch(macroRegex);
+    expect(MACROS.some((m) => m.text === `$__${macro}`)).toBe(true);
+  });
+});
+
+// $__conditionalAll is applied by the frontend as well and has to agree
+describe("applyConditionalAll", () => {
+  const cases = fixtures.cases.filter((c) => c.macro === "conditionalAll");
+
+  test.each(cases)("$name", ({ query, expected }) => {
+    expect(applyConditionalAll(query, emptyContext)).toEqual(expected);
+  });
+});
@@ -46,7 +46,7 @@ export const MACROS = [
   {
     id: "$__timeFilter_ms(dateColumn)",
     name: "$__timeFilter_ms(dateColumn)",
-    text: "$__timeFilter_msf",
+    text: "$__timeFilter_ms",
     args: ["dateColumn"],
     type: MacroType.Filter,
     description:
@@ -98,87 +98,63 @@ describe("MetadataProvider", () => {
   });
 
   test("get schemas", async () => {
-    queryMock.mockReturnValue(
-      of({
-        data: [toDataFrame({ fields: [{ values: SCHEMAS }] })],
-      })
-    );
+    const postResource = jest
+      .spyOn(datasource, "postResource")
+      .mockResolvedValue({ data: SCHEMAS });
     let mdp = getMetadataProvider(datasource);
     let actual = await mdp.schemas();
     expect(actual.map((n) => n.name)).toEqual(SCHEMAS);
-  });
-
-  test("get cached schemas", async () => {
-    queryMock.mockReturnValue(
-      of({
-        data: [toDataFrame({ fields: [{ values: SCHEMAS }] })],
-      })
+    expect(postResource).toHaveBeenCalledWith(
+      "schema/databases",
+      { data: {} },
+      expect.anything()
     );
-    let mdp = getMetadataProvider(datasource);
-    await mdp.schemas();
-    let actual = await mdp.schemas();
-    expect(actual.map((n) => n.name)).toEqual(SCHEMAS);
-    expect(queryMock).toHaveBeenCalledTimes(1);
+    expect(queryMock).not.toHaveBeenCalled();
   });
 
   test("get tables", async () => {
-    queryMock.mockReturnValue(
-      of({
-        data: [toDataFrame({ fields: [{ values: TABLES }] })],
-      })
-    );
+    const postResource = jest
+      .spyOn(datasource, "postResource")
+      .mockResolvedValue({ data: TABLES });
     let mdp = getMetadataProvider(datasource);
     let actual = await mdp.tables({ schema: "schema" });
     expect(actual.map((n) => n.name)).toEqual(TABLES);
+    expect(postResource).toHaveBeenCalledWith(
+      "schema/tables",
+      { data: { database: "schema" } },
+      expect.anything()
+    );
   });
 
-  test("get cached tables", async () => {
-    queryMock.mockReturnValue(
-      of({
-        data: [toDataFrame({ fields: [{ values: TABLES }] })],
-      })
-    );
+  test("get no tables without schema", async () => {
+    const postResource = jest.spyOn(datasource, "postResource");
     let mdp = getMetadataProvider(datasource);
-    await mdp.tables({ schema: "schema" });
-    let actual = await mdp.tables({ schema: "schema" });
-    expect(actual.map((n) => n.name)).toEqual(TABLES);
-    expect(queryMock).toHaveBeenCalledTimes(1);
+    expect(await mdp.tables({})).toEqual([]);
+    expect(postResource).not.toHaveBeenCalled();
   });
 
   test("get columns", async () => {
-    queryMock.mockReturnValue(
-      of({
-        data: [toDataFrame({ fields: [{ values: COLUMNS }] })],
-      })
-    );
+    const postResource = jest
+      .spyOn(datasource, "postResource")
+      .mockResolvedValue({
+        data: COLUMNS.map((name) => ({ name, type: "String" })),
+      });
     let mdp = getMetadataProvider(datasource);
     let actual = await mdp.columns({ schema: "schema", table: "table" });
-    expect(actual.map((n) => n.name)).toEqual(COLUMNS);
-  });
-
-  test("get cached columns", async () => {
-    queryMock.mockReturnValue(
-      of({
-        data: [toDataFrame({ fields: [{ values: COLUMNS }] })],
-      })
+    expect(actual).toEqual(COLUMNS.map((name) => ({ name, type: "String" })));
+    expect(postResource).toHaveBeenCalledWith(
+      "schema/columns",
+      { data: { database: "schema", table: "table" } },
+      expect.anything()
     );
-    let mdp = getMetadataProvider(datasource);
-    await mdp.columns({ schema: "schema", table: "table" });
-    let actual = await mdp.columns({ schema: "schema", table: "table" });
-    expect(actual.map((n) => n.name)).toEqual(COLUMNS);
-    expect(queryMock).toHaveBeenCalledTimes(1);
   });
 
-  test("get non cached", async () => {
-    queryMock.mockReturnValue(
-      of({
-        data: [toDataFrame({ fields: [{ values: COLUMNS }] })],
-      })
-    );
+  test("fail on schema resource errors", async () => {
+    jest
+      .spyOn(datasource, "postResource")
+      .mockRejectedValue({ data: { errorMessage: "connection refused" } });
     let mdp = getMetadataProvider(datasource);
-    await mdp.columns({ schema: "schema", table: "table" });
-    await mdp.columns({ schema: "schema", table: "table2" });
-    expect(queryMock).toHaveBeenCalledTimes(2);
+    await expect(mdp.schemas()).rejects.toThrow("connection refused");
   });
 
   test("get keys", async () => {
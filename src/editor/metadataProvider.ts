@@ -14,15 +14,12 @@ import {
   TableIdentifier,
 } from "@grafana/plugin-ui";
 import { DataSource } from "../datasource";
-import { AdHocFilterKeys } from "../types";
+import { AdHocFilterKeys, ResourceResponse } from "../types";
 import {
   AD_HOC_KEY_QUERY,
-  COLUMNS_SQL,
   FUNCTIONS_SQL,
   NULLABLE_TYPES,
-  SCHEMA_SQL,
   SUPPORTED_TYPES,
-  TABLES_SQL,
   PK_SQL,
   ARRAY_TYPES,
   MAP_TYPES,
@@ -90,8 +87,12 @@ const transformResponse = (r: DataQueryResponse): string[] => {
   return r.data[0]?.fields?.length ? r.data[0].fields[0].values : [];
 };
 
-const transformSchemaResponse = <T>(r: DataQueryResponse): T[] => {
-  return transformResponse(r).map((v: string) => ({ name: v } as T));
+// schema resources fail with an error response rather than rejecting
+const resourceData = <T>(r: ResourceResponse<T>): T => {
+  if (r.error) {
+    throw new Error(r.errorMessage);
+  }
+  return r.data;
 };
 
 const transformFunctionResponse = (
@@ -106,9 +107,6 @@ const transformFunctionResponse = (
 
 export const getMetadataProvider = (ds: DataSource): MetadataProvider => {
   const queryRunner = getQueryRunner(ds);
-  let schemas: SchemaDefinition[] | undefined;
-  let tables: { [table: string]: TableDefinition[] } = {};
-  let columns: { [table: string]: ColumnDefinition[] } = {};
   let tableKeys: { [table: string]: AdHocFilterKeys[] } = {};
   let primaryKeys: { [table: string]: string } = {};
   let functions:
@@ -134,38 +132,18 @@ export const getMetadataProvider = (ds: DataSource): MetadataProvider => {
       : Promise.resolve(tableKeys[table]);
 
   return {
-    schemas: () =>
-      !schemas
-        ? firstValueFrom(
-            queryRunner(SCHEMA_SQL).pipe(
-              map(transformSchemaResponse<SchemaDefinition>),
-              tap((s) => (schemas = s))
-            )
-          )
-        : Promise.resolve(schemas!),
-    tables: (t: TableIdentifier) =>
-      t && !tables[t?.schema!]
-        ? firstValueFrom(
-            queryRunner(TABLES_SQL.replace(/\{schema}/, t?.schema!)).pipe(
-              map(transformSchemaResponse<TableDefinition>),
-              tap((v) => (tables[t.schema!] = v))
-            )
-          )
-        : Promise.resolve(tables[t?.schema!] || []),
-    columns: (t: TableIdentifier) =>
-      !columns[`${t.schema}.${t.table}`]
-        ? firstValueFrom(
-            queryRunner(
-              COLUMNS_SQL.replace(/\{schema}/, t?.schema!).replace(
-                /\{table}/,
-                t?.table!
-              )
-            ).pipe(
-              map(transformSchemaResponse<ColumnDefinition>),
-              tap((v) => (columns[`${t.schema}.${t.table}`] = v))
-            )
-          )
-        : Promise.resolve(columns[`${t.schema}.${t.table}`]!),
+    // databases, tables and columns are cached by the schema resources
+    schemas: async () =>
+      resourceData(await ds.getDatabases()).map((name) => ({ name })),
+    tables: async (t: TableIdentifier) =>
+      t?.schema
+        ? resourceData(await ds.getTables(t.schema)).map((name) => ({ name }))
+        : [],
+    columns: async (t: TableIdentifier) =>
+      resourceData(await ds.getColumns(t.schema!, t.table!)).map((c) => ({
+        name: c.name,
+        type: c.type,
+      })),
     functions: async () => {
       return !functions
         ? firstValueFrom(
@@ -0,0 +1,116 @@
+import { Monaco, monacoTypes } from "@grafana/ui";
+import { registerQuickFixes, setDiagnostics } from "./queryValidation";
+import { Diagnostic } from "../types";
+
+const QUERY = "SELECT count() FROM logs.access";
+
+const DIAGNOSTIC: Diagnostic = {
+  severity: "warning",
+  message: "query of logs.access has no time filter",
+  start: 20,
+  end: 31,
+  line: 1,
+  column: 21,
+  endLine: 1,
+  endColumn: 32,
+  fix: {
+    title: "Add $__timeFilter()",
+    start: 31,
+    end: 31,
+    text: " WHERE $__timeFilter()",
+  },
+};
+
+const model = (value: string) =>
+  ({
+    uri: "inmemory://model/1",
+    getValue: () => value,
+    getVersionId: () => 1,
+    getPositionAt: (offset: number) => ({
+      lineNumber: 1,
+      column: offset + 1,
+    }),
+  } as unknown as monacoTypes.editor.ITextModel);
+
+const setupMonaco = (models: monacoTypes.editor.ITextModel[]) => {
+  const setModelMarkers = jest.fn();
+  const registerCodeActionProvider = jest.fn();
+  const monaco = {
+    MarkerSeverity: { Error: 8, Warning: 4 },
+    editor: { getModels: () => models, setModelMarkers },
+    languages: {
+      getLanguages: () => [{ id: "sql" }, { id: "sql-1" }],
+      registerCodeActionProvider,
+    },
+  } as unknown as Monaco;
+  return { monaco, setModelMarkers, registerCodeActionProvider };
+};
+
+describe("setDiagnostics", () => {
+  it("marks editors holding the validated query", () => {
+    const validated = model(QUERY);
+    const { monaco, setModelMarkers } = setupMonaco([
+      validated,
+      model(`${QUERY} WHERE 1`),
+    ]);
+
+    setDiagnostics(monaco, QUERY, [DIAGNOSTIC]);
+
+    expect(setModelMarkers).toHaveBeenCalledTimes(1);
+    expect(setModelMarkers).toHaveBeenCalledWith(
+      validated,
+      expect.any(String),
+      [
+        {
+          severity: 4,
+          message: DIAGNOSTIC.message,
+          startLineNumber: 1,
+          startColumn: 21,
+          endLineNumber: 1,
+          endColumn: 32,
+        },
+      ]
+    );
+  });
+});
+
+describe("registerQuickFixes", () => {
+  it("offers fixes of diagnostics as quick fixes", () => {
+    const validated = model(QUERY);
+    const { monaco, registerCodeActionProvider } = setupMonaco([validated]);
+    registerQuickFixes(monaco);
+    registerQuickFixes(monaco);
+    expect(registerCodeActionProvider).toHaveBeenCalledTimes(1);
+    expect(registerCodeActionProvider.mock.calls[0][0]).toBe("sql-1");
+    const provider = registerCodeActionProvider.mock.calls[0][1];
+
+    setDiagnostics(monaco, QUERY, [DIAGNOSTIC]);
+    const marker = {
+      message: DIAGNOSTIC.message,
+      startLineNumber: 1,
+      startColumn: 21,
+    };
+    const { actions } = provider.provideCodeActions(validated, null, {
+      markers: [marker],
+    });
+
+    expect(actions).toHaveLength(1);
+    expect(actions[0].title).toBe("Add $__timeFilter()");
+    expect(actions[0].edit.edits[0].textEdit).toEqual({
+      range: {
+        startLineNumber: 1,
+        startColumn: 32,
+        endLineNumber: 1,
+        endColumn: 32,
+      },
+      text: " WHERE $__timeFilter()",
+    });
+
+    // fixes of a query edited since it was validated are dropped
+    validated.getValue = () => `${QUERY} `;
+    expect(
+      provider.provideCodeActions(validated, null, { markers: [marker] })
+        .actions
+    ).toEqual([]);
+  });
+});
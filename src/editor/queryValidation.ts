@@ -1,4 +1,5 @@
-import { ValidationResult } from "../types";
+import { Monaco, monacoTypes } from "@grafana/ui";
+import { Diagnostic, ValidationResult } from "../types";
 import { traverseTree } from "../ast";
 
 const fromWithoutWhere = (ast: any): ValidationResult | null => {
@@ -31,3 +32,113 @@ export const validateQuery = (ast: any): ValidationResult => {
   }
   return {};
 };
+
+const MARKER_OWNER = "hydrolix-validation";
+
+// diagnostics of a model and the text they were reported for
+const modelDiagnostics = new WeakMap<
+  monacoTypes.editor.ITextModel,
+  { query: string; diagnostics: Diagnostic[] }
+>();
+const quickFixLanguages = new Set<string>();
+
+/**
+ * Shows diagnostics of the validate resource as markers of the editors
+ * holding the query. Diagnostics of a query edited since it was sent are
+ * dropped, the next validation replaces them.
+ */
+export const setDiagnostics = (
+  m: Monaco,
+  query: string,
+  diagnostics: Diagnostic[]
+) => {
+  m.editor
+    .getModels()
+    .filter((model) => model.getValue() === query)
+    .forEach((model) => {
+      modelDiagnostics.set(model, { query, diagnostics });
+      m.editor.setModelMarkers(
+        model,
+        MARKER_OWNER,
+        diagnostics.map((d) => ({
+          severity:
+            d.severity === "error"
+              ? m.MarkerSeverity.Error
+              : m.MarkerSeverity.Warning,
+          message: d.message,
+          startLineNumber: d.line,
+          startColumn: d.column,
+          endLineNumber: d.endLine,
+          endColumn: d.endColumn,
+        }))
+      );
+    });
+};
+
+/**
+ * Offers the fixes of diagnostics as quick fixes of their markers, once per
+ * SQL language of the editors
+ */
+export const registerQuickFixes = (m: Monaco) => {
+  m.languages
+    .getLanguages()
+    .map((l) => l.id)
+    .filter((l) => l.startsWith("sql-") && !quickFixLanguages.has(l))
+    .forEach((languageId) => {
+      quickFixLanguages.add(languageId);
+      m.languages.registerCodeActionProvider(languageId, {
+        provideCodeActions: (model, _range, context) => ({
+          actions: context.markers.flatMap((marker) =>
+            quickFix(model, marker)
+          ),
+          dispose: () => {},
+        }),
+      });
+    });
+};
+
+const quickFix = (
+  model: monacoTypes.editor.ITextModel,
+  marker: monacoTypes.editor.IMarkerData
+): monacoTypes.languages.CodeAction[] => {
+  const validated = modelDiagnostics.get(model);
+  if (!validated || validated.query !== model.getValue()) {
+    return [];
+  }
+  const fix = validated.diagnostics.find(
+    (d) =>
+      d.line === marker.startLineNumber &&
+      d.column === marker.startColumn &&
+      d.message === marker.message
+  )?.fix;
+  if (!fix) {
+    return [];
+  }
+  const start = model.getPositionAt(fix.start);
+  const end = model.getPositionAt(fix.end);
+  return [
+    {
+      title: fix.title,
+      kind: "quickfix",
+      diagnostics: [marker],
+      isPreferred: true,
+      edit: {
+        edits: [
+          {
+            resource: model.uri,
+            versionId: model.getVersionId(),
+            textEdit: {
+              range: {
+                startLineNumber: start.lineNumber,
+                startColumn: start.column,
+                endLineNumber: end.lineNumber,
+                endColumn: end.column,
+              },
+              text: fix.text,
+            },
+          },
+        ],
+      },
+    },
+  ];
+};
@@ -1,22 +1,29 @@
 import { VARIABLE_REGEX } from "../constants";
 import { Context } from "types";
 
+const ALL_VALUES = "$__all";
+
+// Same as conditionalAll of pkg/macros/macros.go: a variable left as is
+// (unknown or not interpolated) selects all values, as does an empty value
 export const conditionalAll = (params: string[], context: Context): string => {
-  if (params.length !== 2) {
+  if (params.length < 2) {
     throw new Error("Macro $__conditionalAll should contain 2 parameters");
   }
-  const templateVarParam = params[1].trim();
+  // an interpolated multi-value variable is split into several parameters
+  let value = params.slice(1).join(",").trim();
 
-  const templateVar = VARIABLE_REGEX.exec(templateVarParam);
-  let phrase = params[0];
+  const templateVar = VARIABLE_REGEX.exec(value);
   if (templateVar) {
-    const key = context.templateVars.find(
-      (x) => x.name === templateVar[0]
-    ) as any;
-    let value = key?.current.value.toString();
-    if (value === "" || value === "$__all") {
-      phrase = "1=1";
+    const name = templateVar[1] ?? templateVar[2];
+    const key = context.templateVars.find((x) => x.name === name) as any;
+    if (key?.current?.value === undefined) {
+      return "1=1";
     }
+    value = key.current.value.toString();
+  }
+  const unquoted = value.replace(/^['"]+|['"]+$/g, "");
+  if (unquoted === "" || unquoted === ALL_VALUES) {
+    return "1=1";
   }
-  return phrase;
+  return params[0];
 };
@@ -15,6 +15,8 @@ export interface HdxQuery extends DataQuery {
   skipNextRun?: () => boolean;
   querySettings: QuerySetting[];
   oauthPassThru?: boolean;
+  // Overrides the safe integers mode of the datasource when set
+  safeIntegers?: boolean;
 }
 
 /**
@@ -43,6 +45,10 @@ export interface DataSourceResponse {
 export interface HdxDataSourceOptions extends DataSourceJsonData {
   host?: string;
   port?: number;
+  hosts?: string[];
+  connectionStrategy?: ConnectionStrategy;
+  compression?: Compression;
+  compressionLevel?: number;
   useDefaultPort?: boolean;
   credentialsType?: CredentialsType;
   username?: string;
@@ -50,7 +56,13 @@ export interface HdxDataSourceOptions extends DataSourceJsonData {
   token?: string;
   secure?: boolean;
   path?: string;
+  streamingResult?: boolean;
   skipTlsVerify?: boolean;
+  tlsServerName?: string;
+  proxyUrl?: string;
+  proxyUsername?: string;
+  noProxy?: string[];
+  httpHeaders?: HttpHeader[];
   defaultDatabase?: string;
   defaultRound?: string;
   adHocDefaultTimeRange?: TimeRange;
@@ -58,6 +70,21 @@ export interface HdxDataSourceOptions extends DataSourceJsonData {
   adHocConditionVariable?: string;
   dialTimeout?: string;
   queryTimeout?: string;
+  schemaCacheTTL?: number;
+  maxOpenConns?: number;
+  maxIdleConns?: number;
+  connMaxIdleTime?: number;
+  connMaxLifetime?: number;
+  decimalAsString?: boolean;
+  wideIntegersAsFloat?: boolean;
+  widenFloat32?: boolean;
+  expandTuples?: boolean;
+  flattenJSON?: boolean;
+  geoPointFormat?: GeoPointFormat;
+  safeIntegers?: boolean;
+  typeMappings?: TypeMapping[];
+  maxUserPools?: number;
+  userPoolTTL?: number;
   querySettings?: QuerySetting[];
   exposeErrors?: ExposeErrorsOptions;
   oauthPassThru?: boolean;
@@ -74,6 +101,33 @@ export interface ExposeErrorsOptions {
   ttl?: number;
 }
 
+export interface HttpHeader {
+  name: string;
+  value?: string;
+  // When true, the value is stored in the secure json data under
+  // `httpHeaderValue:<name>`, with the name trimmed, and value is left empty.
+  secure?: boolean;
+}
+
+export interface TypeMapping {
+  // Regular expression matching ClickHouse column types, e.g. ^UInt64$
+  typeRegex: string;
+  fieldType: TypeMappingFieldType;
+  mode?: TypeMappingMode;
+}
+
+export enum TypeMappingFieldType {
+  String = "string",
+  Number = "number",
+  Boolean = "boolean",
+  JSON = "json",
+}
+
+export enum TypeMappingMode {
+  Default = "",
+  Labels = "labels",
+}
+
 export interface QuerySetting {
   setting: string;
   value: string;
@@ -85,6 +139,31 @@ export interface QuerySetting {
 export interface HdxSecureJsonData {
   password?: string;
   token?: string;
+  tlsCACert?: string;
+  tlsClientCert?: string;
+  tlsClientKey?: string;
+  proxyPassword?: string;
+  [httpHeaderValue: `httpHeaderValue:${string}`]: string | undefined;
+}
+
+export enum ConnectionStrategy {
+  InOrder = "in_order",
+  RoundRobin = "round_robin",
+  Random = "random",
+}
+
+export enum GeoPointFormat {
+  LatLon = "latlon",
+  GeoJSON = "geojson",
+}
+
+export enum Compression {
+  None = "none",
+  LZ4 = "lz4",
+  ZSTD = "zstd",
+  GZIP = "gzip",
+  Deflate = "deflate",
+  Brotli = "br",
 }
 
 export enum Protocol {
@@ -107,14 +186,53 @@ export interface AdHocFilterKeys {
 
 export interface MacroCTEResponse extends ResourceResponse<MacroCTE[]> {}
 export interface InterpolationResponse extends ResourceResponse<string> {}
+export interface ValidationResponse extends ResourceResponse<Diagnostic[]> {}
+export interface FormatResponse extends ResourceResponse<string> {}
+export interface SchemaListResponse extends ResourceResponse<string[]> {}
+export interface ColumnsResponse extends ResourceResponse<SchemaColumn[]> {}
+
+/** Column of a table listed by the schema resource */
+export interface SchemaColumn {
+  name: string;
+  type: string;
+}
+
+/** Style of SQL formatted by the format resource */
+export interface FormatOptions {
+  keywordCase?: "upper" | "lower" | "preserve";
+  indentWidth?: number;
+}
 
 export interface ResourceResponse<T> {
   originalSql: string;
   error: boolean;
   errorMessage: string;
+  /** Code of failed requests */
+  errorCode?: ResourceErrorCode;
+  /** Position of parser errors in the query */
+  position?: ErrorPosition;
   data: T;
 }
 
+/** Error codes of resource routes, see pkg/api/errors.go */
+export enum ResourceErrorCode {
+  BadRequest = "BAD_REQUEST",
+  InvalidQuery = "INVALID_QUERY",
+  Database = "DATABASE_ERROR",
+  Internal = "INTERNAL_ERROR",
+}
+
+/**
+ * Location of an error in a query, offsets are indices of the query string,
+ * line and column are 1-based
+ */
+export interface ErrorPosition {
+  start: number;
+  end: number;
+  line: number;
+  column: number;
+}
+
 export interface InterpolationResult {
   originalSql: string;
   interpolationId: string;
@@ -131,6 +249,33 @@ export interface ValidationResult {
   warning?: string;
 }
 
+/**
+ * Problem of a query reported by the validate resource. Offsets are indices of
+ * the query string, lines and columns are 1-based like Monaco editor positions.
+ */
+export interface Diagnostic {
+  severity: "error" | "warning";
+  message: string;
+  start: number;
+  end: number;
+  line: number;
+  column: number;
+  endLine: number;
+  endColumn: number;
+  fix?: DiagnosticFix;
+}
+
+/**
+ * Suggested fix of a diagnostic, replaces the query between start and end
+ * offsets by text
+ */
+export interface DiagnosticFix {
+  title: string;
+  start: number;
+  end: number;
+  text: string;
+}
+
 export interface SelectQuery {
   SelectItems: SelectItem[];
   From: Expr;